@@ -0,0 +1,190 @@
+package checksum
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// extensions are the file extensions of the artifacts of the dist directory
+// that are verified before they are used.
+var extensions = []string{".pkg", ".dmg", ".app", ".sh"}
+
+// VerifyError is the error of a file that failed the checksum verification.
+type VerifyError struct {
+	Path     string
+	Expected string
+	// Actual is the checksum of the file, it is empty if the file could not be read.
+	Actual string
+	Err    error
+}
+
+func (e *VerifyError) Error() string {
+	if e.Err != nil {
+		return fmt.Sprintf("failed to verify checksum of %s: %v", e.Path, e.Err)
+	}
+
+	return fmt.Sprintf("checksum mismatch for %s, expected %s but got %s", e.Path, e.Expected, e.Actual)
+}
+
+func (e *VerifyError) Unwrap() error {
+	return e.Err
+}
+
+// Sum returns the hex encoded SHA-256 checksum of the file.
+//
+// A directory, such as an app bundle, is summed from the relative path and the
+// checksum of each of its files in lexical order. Symbolic links are summed
+// from their target and are not followed.
+func Sum(path string) (string, error) {
+	info, err := os.Lstat(path)
+	if err != nil {
+		return "", err
+	}
+
+	if !info.IsDir() {
+		return sumFile(path)
+	}
+
+	hash := sha256.New()
+	walk := func(filePath string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			return nil
+		}
+
+		rel, err := filepath.Rel(path, filePath)
+		if err != nil {
+			return err
+		}
+
+		entry := ""
+		if d.Type()&fs.ModeSymlink != 0 {
+			target, err := os.Readlink(filePath)
+			if err != nil {
+				return err
+			}
+
+			entry = "-> " + target
+		} else {
+			entry, err = sumFile(filePath)
+			if err != nil {
+				return err
+			}
+		}
+
+		fmt.Fprintf(hash, "%s\x00%s\n", filepath.ToSlash(rel), entry)
+
+		return nil
+	}
+
+	err = filepath.WalkDir(path, walk)
+	if err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// sumFile returns the hex encoded SHA-256 checksum of the contents of a file.
+func sumFile(path string) (string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	hash := sha256.New()
+	_, err = io.Copy(hash, file)
+	if err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// Verify compares the checksum of the file with the expected checksum, the case
+// of the expected checksum is ignored.
+//
+// A VerifyError is returned if the checksums do not match or the file could not be read.
+func Verify(path string, expected string) error {
+	expected = strings.ToLower(strings.TrimSpace(expected))
+
+	actual, err := Sum(path)
+	if err != nil {
+		return &VerifyError{Path: path, Expected: expected, Err: err}
+	}
+
+	if actual != expected {
+		return &VerifyError{Path: path, Expected: expected, Actual: actual}
+	}
+
+	return nil
+}
+
+// IsValid returns true if the value is a hex encoded SHA-256 checksum.
+func IsValid(value string) bool {
+	if len(value) != sha256.Size*2 {
+		return false
+	}
+
+	_, err := hex.DecodeString(value)
+
+	return err == nil
+}
+
+// Generate returns the checksums of the packages, DMGs, apps and scripts found in
+// the directory by the file name. The contents of the bundles are not searched.
+//
+// An error is returned if the directory fails to be read, or if two files have the
+// same file name, as the checksums are looked up by the file name.
+func Generate(dir string) (map[string]string, error) {
+	checksums := map[string]string{}
+	paths := map[string]string{}
+
+	walk := func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		name := d.Name()
+		ext := strings.ToLower(filepath.Ext(name))
+		if path == dir || !slices.Contains(extensions, ext) {
+			return nil
+		}
+
+		key := strings.ToLower(name)
+		if prev, ok := paths[key]; ok {
+			return fmt.Errorf("file name %s is used by %s and %s", name, prev, path)
+		}
+
+		sum, err := Sum(path)
+		if err != nil {
+			return err
+		}
+
+		checksums[name] = sum
+		paths[key] = path
+
+		if d.IsDir() {
+			return fs.SkipDir
+		}
+
+		return nil
+	}
+
+	err := filepath.WalkDir(dir, walk)
+	if err != nil {
+		return nil, err
+	}
+
+	return checksums, nil
+}
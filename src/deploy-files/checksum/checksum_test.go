@@ -0,0 +1,117 @@
+package checksum
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/bobllor/assert"
+)
+
+// helloSum is the SHA-256 checksum of "hello\n".
+const helloSum string = "5891b5b522d5df086d0ff0b110fbd9d21bb4fc7163af34d08286a2e846f6be03"
+
+func writeFile(t *testing.T, path string, content string) {
+	t.Helper()
+
+	err := os.MkdirAll(filepath.Dir(path), 0o755)
+	if err != nil {
+		t.Fatalf("failed to create directory: %v", err)
+	}
+
+	err = os.WriteFile(path, []byte(content), 0o644)
+	if err != nil {
+		t.Fatalf("failed to write file: %v", err)
+	}
+}
+
+func TestSum(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "package.pkg")
+	writeFile(t, path, "hello\n")
+
+	sum, err := Sum(path)
+	assert.Nil(t, err)
+	assert.Equal(t, sum, helloSum)
+
+	t.Run("Bundle", func(t *testing.T) {
+		app := filepath.Join(t.TempDir(), "Test.app")
+		writeFile(t, filepath.Join(app, "Contents", "Info.plist"), "plist")
+		writeFile(t, filepath.Join(app, "Contents", "MacOS", "Test"), "binary")
+
+		sum, err := Sum(app)
+		assert.Nil(t, err)
+
+		again, err := Sum(app)
+		assert.Nil(t, err)
+		assert.Equal(t, sum, again)
+
+		writeFile(t, filepath.Join(app, "Contents", "MacOS", "Test"), "changed")
+
+		changed, err := Sum(app)
+		assert.Nil(t, err)
+		assert.Equal(t, sum == changed, false)
+	})
+}
+
+func TestVerify(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "script.sh")
+	writeFile(t, path, "hello\n")
+
+	assert.Nil(t, Verify(path, strings.ToUpper(helloSum)))
+
+	t.Run("Mismatch", func(t *testing.T) {
+		expected := strings.Repeat("a", 64)
+
+		err := Verify(path, expected)
+
+		var verifyErr *VerifyError
+		assert.Equal(t, errors.As(err, &verifyErr), true)
+		assert.Equal(t, verifyErr.Expected, expected)
+		assert.Equal(t, verifyErr.Actual, helloSum)
+		assert.Nil(t, verifyErr.Err)
+	})
+
+	t.Run("Missing File", func(t *testing.T) {
+		err := Verify(filepath.Join(t.TempDir(), "missing.sh"), helloSum)
+
+		var verifyErr *VerifyError
+		assert.Equal(t, errors.As(err, &verifyErr), true)
+		assert.Equal(t, verifyErr.Actual, "")
+		assert.Equal(t, errors.Is(err, os.ErrNotExist), true)
+	})
+}
+
+func TestIsValid(t *testing.T) {
+	assert.Equal(t, IsValid(helloSum), true)
+	assert.Equal(t, IsValid(strings.ToUpper(helloSum)), true)
+	assert.Equal(t, IsValid(helloSum[1:]), false)
+	assert.Equal(t, IsValid(strings.Repeat("z", 64)), false)
+}
+
+func TestGenerate(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "package.pkg"), "hello\n")
+	writeFile(t, filepath.Join(dir, "nested", "image.dmg"), "dmg")
+	writeFile(t, filepath.Join(dir, "Test.app", "Contents", "Info.plist"), "plist")
+	writeFile(t, filepath.Join(dir, "scripts", "setup.sh"), "echo")
+	writeFile(t, filepath.Join(dir, "macdeploy"), "binary")
+
+	checksums, err := Generate(dir)
+	assert.Nil(t, err)
+
+	assert.Equal(t, len(checksums), 4)
+	assert.Equal(t, checksums["package.pkg"], helloSum)
+
+	appSum, err := Sum(filepath.Join(dir, "Test.app"))
+	assert.Nil(t, err)
+	assert.Equal(t, checksums["Test.app"], appSum)
+
+	t.Run("Duplicate File Name", func(t *testing.T) {
+		writeFile(t, filepath.Join(dir, "other", "Package.pkg"), "other")
+
+		_, err := Generate(dir)
+		assert.NotNil(t, err)
+	})
+}
@@ -0,0 +1,39 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/bobllor/macdeploy/src/deploy-files/checksum"
+	"github.com/bobllor/macdeploy/src/deploy-files/yaml"
+)
+
+// main is used to compute the checksums of the artifacts in a dist directory and
+// print them as the 'checksums' field of the YAML config.
+// If no path is given, then the dist directory of the working directory is used.
+// It will exit 1 if the directory fails to be read.
+func main() {
+	dir := "dist"
+	if len(os.Args) > 1 {
+		dir = os.Args[1]
+	}
+
+	checksums, err := checksum.Generate(dir)
+	if err != nil {
+		fmt.Printf("Failed to generate checksums of %s: %v\n", dir, err)
+		os.Exit(1)
+	}
+
+	if len(checksums) == 0 {
+		fmt.Printf("No packages, DMGs, apps or scripts found in %s\n", dir)
+		os.Exit(1)
+	}
+
+	out, err := yaml.Marshal(map[string]map[string]string{"checksums": checksums})
+	if err != nil {
+		fmt.Printf("Failed to write checksums: %v\n", err)
+		os.Exit(1)
+	}
+
+	fmt.Print(string(out))
+}
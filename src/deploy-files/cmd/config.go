@@ -0,0 +1,209 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	embedhandler "github.com/bobllor/macdeploy/src/config"
+	"github.com/bobllor/macdeploy/src/deploy-files/prompt"
+	"github.com/bobllor/macdeploy/src/deploy-files/yaml"
+)
+
+// configFile is the file name of the YAML config next to the binary.
+const configFile string = "config.yml"
+
+// embeddedConfig is the source name of the embedded YAML config.
+const embeddedConfig string = "embedded"
+
+// overlayProfileKey is the key of the device config overlay used to assign a profile.
+const overlayProfileKey string = "profile"
+
+// readConfig returns the data of the YAML config and the path it was read from.
+// The config is searched in the order:
+//  1. The path given with the --config flag.
+//  2. The file config.yml in the same directory as the binary.
+//  3. The config embedded in the binary.
+//
+// An error is returned if the config file exists but fails to be read.
+func (r *RootData) readConfig() ([]byte, string, error) {
+	if r.ConfigPath != "" {
+		data, err := os.ReadFile(r.ConfigPath)
+		if err != nil {
+			return nil, "", fmt.Errorf("failed to read config file %s: %v", r.ConfigPath, err)
+		}
+
+		return data, r.ConfigPath, nil
+	}
+
+	exe, err := os.Executable()
+	if err == nil {
+		path := filepath.Join(filepath.Dir(exe), configFile)
+
+		data, err := os.ReadFile(path)
+		if err == nil {
+			return data, path, nil
+		}
+		if !errors.Is(err, os.ErrNotExist) {
+			return nil, "", fmt.Errorf("failed to read config file %s: %v", path, err)
+		}
+	}
+
+	return embedhandler.YAMLBytes, embeddedConfig, nil
+}
+
+// loadConfig reads the YAML config and applies the deployment profile and the
+// config overlay of the device from the server.
+//
+// The profile is taken from the flag, then the overlay of the device. If neither
+// has a profile, then the profile is selected with a prompt. The overlay and the
+// prompt are not used for sub commands.
+//
+// An error is returned if the config fails to parse or the profile does not exist.
+func (r *RootData) loadConfig(serialTag string, isSubCommand bool) (*yaml.Config, error) {
+	data, source, err := r.readConfig()
+	if err != nil {
+		return nil, err
+	}
+	r.configSource = source
+
+	if source != embeddedConfig && !isSubCommand {
+		fmt.Printf("Using config file %s\n", source)
+	}
+
+	config, err := yaml.NewConfig(data)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing YAML configuration %s, %v", source, err)
+	}
+
+	overlay := map[string]any{}
+	if !isSubCommand {
+		var dropped []string
+		overlay, dropped = yaml.FilterOverlay(r.getDeviceOverlay(config.ServerHost, serialTag))
+
+		if len(dropped) > 0 {
+			r.log.Warnf("Ignored keys of the device config from the server: %s", strings.Join(dropped, ", "))
+			fmt.Printf("Ignored keys not allowed in the device config from the server: %s\n", strings.Join(dropped, ", "))
+		}
+	}
+
+	// the profile flag takes precedent over the profile assigned by the server.
+	if profile, ok := overlay[overlayProfileKey].(string); ok && r.Profile == "" {
+		if slices.Contains(config.ProfileNames(), profile) {
+			fmt.Printf("Using profile %s assigned by the server\n", profile)
+			r.Profile = profile
+		} else {
+			r.log.Warnf("Profile %s assigned by the server does not exist in the config", profile)
+			fmt.Printf("Profile %s assigned by the server does not exist, ignoring it\n", profile)
+		}
+	}
+	delete(overlay, overlayProfileKey)
+
+	if r.Profile == "" && !isSubCommand {
+		r.Profile, err = r.selectProfile(config)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if r.Profile != "" {
+		data, err = yaml.ApplyProfile(data, r.Profile)
+		if err != nil {
+			return nil, err
+		}
+
+		config, err = yaml.NewConfig(data)
+		if err != nil {
+			return nil, fmt.Errorf("error parsing YAML configuration with profile '%s', %v", r.Profile, err)
+		}
+	}
+
+	if len(overlay) == 0 {
+		return config, nil
+	}
+
+	overlayConfig, err := applyOverlay(data, overlay)
+	if err != nil {
+		r.log.Warnf("Failed to apply the device config from the server: %v", err)
+		fmt.Println("Device config from the server is invalid, using the config without it")
+
+		return config, nil
+	}
+
+	r.configOverlay = true
+	fmt.Println("Applied device config from the server")
+
+	return overlayConfig, nil
+}
+
+// getDeviceOverlay returns the config overlay of the device from the server.
+// An empty overlay is returned if the device has no overlay or the server cannot be reached.
+func (r *RootData) getDeviceOverlay(host string, serialTag string) map[string]any {
+	if host == "" || serialTag == "UNKNOWN" {
+		return map[string]any{}
+	}
+
+	request := r.newRequest()
+
+	res, err := request.GetDeviceConfig(host, serialTag)
+	if err != nil {
+		r.log.Warnf("Failed to query device config: %v", err)
+		fmt.Println("Unable to get the device config from the server, using the local config")
+
+		return map[string]any{}
+	}
+
+	r.log.Debugf("Device config status: %s | Message: %s", res.Status, res.Message)
+
+	return res.Content
+}
+
+// applyOverlay merges the overlay onto the config data and validates the result.
+func applyOverlay(data []byte, overlay map[string]any) (*yaml.Config, error) {
+	data, err := yaml.Overlay(data, overlay)
+	if err != nil {
+		return nil, err
+	}
+
+	config, err := yaml.NewConfig(data)
+	if err != nil {
+		return nil, err
+	}
+
+	err = yaml.Validate(config)
+	if err != nil {
+		return nil, err
+	}
+
+	return config, nil
+}
+
+// selectProfile prompts for the profile of the deployment. An empty string is returned
+// if the config has no profiles or no profile was chosen, which uses the base config.
+//
+// The base config is used without a prompt in a dry run or in non-interactive mode.
+func (r *RootData) selectProfile(config *yaml.Config) (string, error) {
+	names := config.ProfileNames()
+	if len(names) == 0 {
+		return "", nil
+	}
+
+	if r.dep.runner.DryRun() || !prompt.Interactive() {
+		fmt.Println("No profile given, using the base config")
+		return "", nil
+	}
+
+	profile, err := prompt.Select("deployment profile", names)
+	if err != nil {
+		return "", err
+	}
+
+	if profile == "" {
+		fmt.Println("No profile selected, using the base config")
+	}
+
+	return profile, nil
+}
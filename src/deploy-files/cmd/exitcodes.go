@@ -0,0 +1,88 @@
+package cmd
+
+import (
+	"github.com/bobllor/macdeploy/src/deploy-files/pipeline"
+)
+
+// ExitCode is the exit code of the binary.
+//
+// A degraded deployment is a combination of the failure bits, e.g. 6 means both the
+// packages and the accounts have failed. The failure bits are always even and below 128,
+// which is used by the shell for a process killed by a signal.
+//
+// ExitError, ExitConfig and ExitAborted are odd and are never combined with the failure
+// bits, an odd exit code means the deployment did not run or did not finish.
+type ExitCode int
+
+const (
+	ExitSuccess ExitCode = 0
+	// ExitError is a general error, such as an invalid flag or a deployment that failed to run.
+	ExitError ExitCode = 1
+	// ExitPackages is a failure to install one or more packages.
+	ExitPackages ExitCode = 1 << 1
+	// ExitAccounts is a failure to create an account or to add its secure token.
+	ExitAccounts ExitCode = 1 << 2
+	// ExitFileVault is a failure to enable FileVault, to send the key to the server or to enable the Firewall.
+	ExitFileVault ExitCode = 1 << 3
+	// ExitServer is a failure to send the log or the report to the server.
+	ExitServer ExitCode = 1 << 4
+	// ExitScripts is a failure of one or more scripts.
+	ExitScripts ExitCode = 1 << 5
+	// ExitTimeout is a stage or a command that reached its time limit.
+	ExitTimeout ExitCode = 1 << 6
+	// ExitConfig is an invalid config, the deployment did not start.
+	ExitConfig ExitCode = 3
+	// ExitAborted is a deployment stopped by SIGINT or SIGTERM, it can be resumed.
+	ExitAborted ExitCode = 5
+)
+
+// stageExitCodes are the exit codes of the failed stages. Stages that are not found
+// are the stages from the config, which only run scripts.
+var stageExitCodes = map[string]ExitCode{
+	stageAccounts:  ExitAccounts,
+	stageDmg:       ExitPackages,
+	stagePackages:  ExitPackages,
+	stageApps:      ExitPackages,
+	stageFileVault: ExitFileVault,
+	stagePolicy:    ExitAccounts,
+	stageLog:       ExitServer,
+	stageReport:    ExitServer,
+	stageFirewall:  ExitFileVault,
+}
+
+// exitError is an error that exits the binary with its code.
+type exitError struct {
+	code ExitCode
+	err  error
+}
+
+func (e *exitError) Error() string {
+	return e.err.Error()
+}
+
+func (e *exitError) Unwrap() error {
+	return e.err
+}
+
+// exitCodeFromResults returns the combined exit code of the failed stages.
+// A timed out stage is a failed stage.
+func exitCodeFromResults(results []pipeline.Result) ExitCode {
+	code := ExitSuccess
+
+	for _, result := range results {
+		if result.Status == pipeline.StatusTimedOut {
+			code |= ExitTimeout
+		} else if result.Status != pipeline.StatusFailed {
+			continue
+		}
+
+		stageCode, ok := stageExitCodes[result.Name]
+		if !ok {
+			stageCode = ExitScripts
+		}
+
+		code |= stageCode
+	}
+
+	return code
+}
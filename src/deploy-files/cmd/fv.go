@@ -55,9 +55,11 @@ var fvDisableCmd = &cobra.Command{
 		root.initialize(true)
 	},
 	Run: func(cmd *cobra.Command, args []string) {
+		fvCobra.User.SetFromEnv(envAdminUsername, envAdminPassword)
+
 		if fvCobra.User.Username == "" {
 			fmt.Println("No username given, using current logged in user")
-			err := fvCobra.User.SetUsername()
+			err := fvCobra.User.SetUsername(root.dep.runner)
 			if err != nil {
 				fmt.Println(err)
 				root.log.Fatal(err)
@@ -66,13 +68,14 @@ var fvDisableCmd = &cobra.Command{
 		}
 		if fvCobra.User.Password == "" {
 			fmt.Println("No admin password given")
-			err := fvCobra.User.SetPassword(false)
+			err := fvCobra.User.SetPassword(root.dep.runner, false)
 			if err != nil {
 				fmt.Println(err)
 				root.log.Fatal(err)
 				return
 			}
 		}
+		root.log.AddSecrets(fvCobra.User.Password)
 
 		_, err := root.dep.filevault.Disable(fvCobra.User.Username, fvCobra.User.Password)
 		if err != nil {
@@ -119,7 +122,7 @@ var fvEnableCmd = &cobra.Command{
 		if fvCobra.User.Username == "" {
 			fmt.Println("No username given, setting username")
 			// this is root config as startFileVault uses root.
-			err := root.config.Admin.SetUsername()
+			err := root.config.Admin.SetUsername(root.dep.runner)
 			if err != nil {
 				fmt.Println(err)
 				root.log.Fatal(err)
@@ -129,15 +132,16 @@ var fvEnableCmd = &cobra.Command{
 		if fvCobra.User.Password == "" {
 			fmt.Println("No password given, input required")
 			// this is root config as startFileVault uses root.
-			err := root.config.Admin.SetPassword(false)
+			err := root.config.Admin.SetPassword(root.dep.runner, false)
 			if err != nil {
 				fmt.Println(err)
 				root.log.Fatal(err)
 				return
 			}
+			root.log.AddSecrets(root.config.Admin.Password)
 		}
 
-		r := requests.NewRequest(root.log)
+		r := root.newRequest()
 		key := root.startFileVault(root.dep.filevault, r)
 
 		keyPayload := requests.NewFileVaultPayload(key)
@@ -0,0 +1,139 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"slices"
+	"syscall"
+	"time"
+)
+
+// handleInterrupts creates the context of the deployment and cancels it on SIGINT or SIGTERM.
+// The running stage and its commands are stopped, and the stages after it do not run.
+//
+// The signals are handled until the binary exits. A second signal kills the running commands
+// and their children, then exits the binary without the cleanup.
+// It returns a function that stops handling the signals.
+func (r *RootData) handleInterrupts() func() {
+	ctx, cancel := context.WithCancelCause(context.Background())
+	r.ctx = ctx
+	r.cancel = cancel
+	r.dep.runner.SetContext(ctx)
+
+	ch := make(chan os.Signal, 1)
+	done := make(chan struct{})
+	signal.Notify(ch, os.Interrupt, syscall.SIGTERM)
+
+	go func() {
+		interrupted := false
+
+		for {
+			select {
+			case sig := <-ch:
+				if interrupted {
+					r.kill(sig)
+					return
+				}
+				interrupted = true
+
+				r.log.Warnf("Received %s, aborting the deployment", sig)
+				fmt.Printf("\nReceived %s, stopping the deployment (interrupt again to exit immediately)\n", sig)
+
+				cancel(fmt.Errorf("received %s", sig))
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	return func() {
+		signal.Stop(ch)
+		close(done)
+	}
+}
+
+// kill kills the process groups of the running commands and exits the binary with ExitAborted.
+// The commands run in their own process groups, they do not receive the signals of the terminal.
+func (r *RootData) kill(sig os.Signal) {
+	r.log.Critical(fmt.Sprintf("Received %s again, exiting without the cleanup", sig))
+	fmt.Printf("\nReceived %s again, exiting\n", sig)
+
+	for _, command := range r.dep.runner.Running() {
+		err := syscall.Kill(-command.Pid, syscall.SIGKILL)
+		if err != nil && !errors.Is(err, syscall.ESRCH) {
+			r.log.Warnf("Failed to kill %s: %v", command.Command, err)
+			continue
+		}
+
+		r.log.Warnf("Killed %s", command.Command)
+	}
+
+	if r.osFile != nil {
+		r.osFile.Close()
+	}
+
+	os.Exit(int(ExitAborted))
+}
+
+// aborted returns true if the deployment was interrupted.
+func (r *RootData) aborted() bool {
+	return r.ctx != nil && r.ctx.Err() != nil
+}
+
+// abort cleans up an interrupted deployment. The mounted DMG volumes are detached, and the
+// partial log and the report are saved and sent to the server with an aborted status.
+// The state journal keeps the completed stages, which are skipped with --resume.
+func (r *RootData) abort() {
+	// the deployment context is canceled, the cleanup uses a new context.
+	r.dep.runner.SetContext(context.Background())
+
+	r.log.Critical(fmt.Sprintf("Deployment aborted: %v", context.Cause(r.ctx)))
+	fmt.Println("Deployment aborted, cleaning up")
+
+	volumes := r.dep.filehandler.MountedVolumes()
+	if len(volumes) > 0 {
+		r.dep.filehandler.DetachDmgs(volumes)
+	}
+
+	r.exitCode = ExitAborted
+	r.log.Infof("Deployment exit code: %d", r.exitCode)
+
+	r.report.Aborted = true
+	r.report.ExitCode = int(r.exitCode)
+	r.updateReport()
+	r.report.Finish()
+	r.saveReport()
+	r.saveJournal()
+
+	plan, err := r.pipeline.Plan()
+	if err != nil {
+		r.log.Warnf("Failed to plan stages, the report and the log are not sent: %v", err)
+		plan = []string{}
+	}
+
+	// the firewall blocks the requests once it is enabled.
+	sendable := func(stage string) bool {
+		return slices.Contains(plan, stage) && !r.pipeline.Skipped(stage) &&
+			!slices.Contains(r.journal.CompletedStages(), stageFirewall)
+	}
+
+	if sendable(stageReport) {
+		err := r.sendReport()
+		if err != nil {
+			r.log.Warnf("Failed to send the aborted report: %v", err)
+		}
+	}
+	if sendable(stageLog) {
+		currDate := time.Now().Format("2006-01-02")
+
+		err := r.sendLog(fmt.Sprintf("%s.%s.aborted.log", r.metadata.SerialTag, currDate))
+		if err != nil {
+			r.log.Warnf("Failed to send the aborted log: %v", err)
+		}
+	}
+
+	fmt.Printf("Deployment aborted for %s, run 'macdeploy --resume' to continue\n", r.metadata.SerialTag)
+}
@@ -0,0 +1,89 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	requests "github.com/bobllor/macdeploy/src/deploy-files/server-requests"
+)
+
+// reportFile is the file name of the deployment report, it is stored in the log directory.
+const reportFile string = "report.json"
+
+// getReportPath returns the path of the deployment report.
+func getReportPath() string {
+	return fmt.Sprintf("%s/%s/%s", os.Getenv("HOME"), defaultLogDir, reportFile)
+}
+
+// updateReport updates the deployment report with the current state of the device.
+func (r *RootData) updateReport() {
+	handler := r.dep.filehandler
+
+	r.report.Packages.Installed = handler.GetInstalledPackages()
+	r.report.Packages.Skipped = handler.GetSkippedPackages()
+	r.report.Packages.SkippedByCondition = handler.GetConditionSkippedPackages()
+	r.report.Packages.Failed = handler.GetFailedPackages()
+	r.report.SetChecksumFailures(handler.GetChecksumErrors())
+	r.report.UsersCreated = r.journal.AccountsCreated
+	r.report.FileVault.KeyEscrowed = r.journal.KeyEscrowed
+	r.report.TimedOut = r.dep.runner.TimedOut()
+
+	fvStatus, err := r.dep.filevault.Status()
+	if err != nil {
+		r.log.Warnf("Failed to check FileVault status for the report: %v", err)
+	}
+	r.report.FileVault.Enabled = fvStatus
+
+	firewallStatus, err := r.dep.firewall.Status()
+	if err != nil {
+		r.log.Warnf("Failed to check Firewall status for the report: %v", err)
+	}
+	r.report.Firewall.Enabled = firewallStatus
+}
+
+// saveReport writes the deployment report to the disk. Nothing is written during a dry run.
+func (r *RootData) saveReport() {
+	reportPath := getReportPath()
+
+	if r.dep.runner.DryRun() {
+		r.dep.runner.Planf("write report %s", reportPath)
+		return
+	}
+
+	err := r.report.Save(reportPath)
+	if err != nil {
+		r.log.Warnf("Failed to save deployment report to %s: %v", reportPath, err)
+		return
+	}
+
+	r.log.Infof("Saved deployment report to %s", reportPath)
+}
+
+// runReportStage saves the deployment report and sends it to the server.
+func (r *RootData) runReportStage() error {
+	r.log.Info("Sending deployment report to the server")
+
+	r.updateReport()
+	r.saveReport()
+
+	return r.sendReport()
+}
+
+// sendReport sends the deployment report to the server.
+// Stages that have not ran yet are sent as pending.
+func (r *RootData) sendReport() error {
+	plan, err := r.pipeline.Plan()
+	if err != nil {
+		return err
+	}
+
+	reportPayload := requests.NewReportPayload(r.report.WithPending(plan))
+	reportPayload.SetBody(r.metadata.SerialTag)
+
+	err = r.startRequest(reportPayload, r.newRequest(), r.config.ServerHost, "/api/report")
+	if err != nil {
+		return fmt.Errorf("failed to send report to server: %v", err)
+	}
+
+	return nil
+}
@@ -2,6 +2,7 @@ package cmd
 
 import (
 	"bufio"
+	"context"
 	"errors"
 	"fmt"
 	"log"
@@ -10,11 +11,15 @@ import (
 	"strings"
 	"time"
 
-	embedhandler "github.com/bobllor/macdeploy/src/config"
 	"github.com/bobllor/macdeploy/src/deploy-files/core"
 	"github.com/bobllor/macdeploy/src/deploy-files/logger"
+	"github.com/bobllor/macdeploy/src/deploy-files/pipeline"
+	"github.com/bobllor/macdeploy/src/deploy-files/prompt"
+	"github.com/bobllor/macdeploy/src/deploy-files/report"
+	"github.com/bobllor/macdeploy/src/deploy-files/runner"
 	"github.com/bobllor/macdeploy/src/deploy-files/scripts"
 	requests "github.com/bobllor/macdeploy/src/deploy-files/server-requests"
+	"github.com/bobllor/macdeploy/src/deploy-files/state"
 	"github.com/bobllor/macdeploy/src/deploy-files/utils"
 	"github.com/bobllor/macdeploy/src/deploy-files/yaml"
 
@@ -53,13 +58,41 @@ type RootData struct {
 	// config file.
 	ExcludePackages []string
 
+	// SkipStages is a slice of stage names to skip during the deployment.
+	SkipStages []string
+
 	// IncludePackages is a slice of packages to install. These must be in the 'dist/'
 	// directory.
 	IncludePackages []string
 
+	// Resume continues the deployment from the state journal, skipping the completed stages.
+	Resume bool
+
+	// NonInteractive disables all prompts. Inputs must be given with the config,
+	// flags or environment variables.
+	NonInteractive bool
+
+	// DryRun prints the commands and requests of the deployment instead of running them.
+	DryRun bool
+
 	// PlistPath is a path to a plist file, used for password policies.
 	PlistPath string
 
+	// ConfigPath is a path to a YAML config file used instead of the embedded config.
+	ConfigPath string
+
+	// Profile is the name of the config profile applied on top of the base config.
+	Profile string
+
+	// configSource is the path of the YAML config, or "embedded" for the embedded config.
+	configSource string
+
+	// configOverlay indicates that the config overlay of the device from the server was applied.
+	configOverlay bool
+
+	// runID is the unique ID of the deployment run, it is given to the scripts.
+	runID string
+
 	// logFile the logging file name.
 	logFile string
 
@@ -88,6 +121,27 @@ type RootData struct {
 	// dep are the main dependencies used for the core process of the deployment.
 	dep dependencies
 
+	// exitCode is the exit code of the deployment, the failures of the stages are added to it.
+	exitCode ExitCode
+
+	// pipeline runs the stages of the deployment process.
+	pipeline *pipeline.Pipeline
+
+	// journal is the state of the deployment, it is saved after each stage.
+	journal *state.Journal
+
+	// report is the summary of the deployment, it is saved when the deployment is done.
+	report *report.Report
+
+	// ctx is the context of the deployment, it is canceled by an interrupt.
+	ctx context.Context
+
+	// cancel cancels the context of the deployment with the cause, which aborts the deployment.
+	cancel context.CancelCauseFunc
+
+	// stopInterrupts stops handling the interrupts of the deployment.
+	stopInterrupts func()
+
 	// perm are file modes for file creation.
 	perm *utils.Perms
 
@@ -109,6 +163,8 @@ type dependencies struct {
 	usermaker   *core.UserMaker
 	filevault   *core.FileVault
 	firewall    *core.Firewall
+	sudo        *core.SudoSession
+	runner      *runner.System
 }
 
 type varData struct {
@@ -121,6 +177,14 @@ const (
 	defaultLogDir string = "logs/macdeploy"
 )
 
+// environment variables used for inputs instead of prompts.
+const (
+	envAdminUsername string = "MACDEPLOY_ADMIN_USERNAME"
+	envAdminPassword string = "MACDEPLOY_ADMIN_PASSWORD"
+	envLocalUsername string = "MACDEPLOY_LOCAL_USERNAME"
+	envLocalPassword string = "MACDEPLOY_LOCAL_PASSWORD"
+)
+
 var root RootData
 
 var paddingMsg int = 2
@@ -141,9 +205,24 @@ var rootCmd = &cobra.Command{
 		if root.SkipFileVault && root.ForceFileVault {
 			return fmt.Errorf("--skipfilevault and --forcefilevault cannot be used together")
 		}
+		if slices.Contains(root.SkipStages, stageFileVault) && root.ForceFileVault {
+			return fmt.Errorf("--skip %s and --forcefilevault cannot be used together", stageFileVault)
+		}
 
 		root.initialize(false)
 
+		p, err := root.newPipeline()
+		if err != nil {
+			return &exitError{code: ExitConfig, err: fmt.Errorf("invalid deployment stages: %v", err)}
+		}
+		root.pipeline = p
+
+		err = root.initJournal(root.Resume)
+		if err != nil {
+			return fmt.Errorf("failed to initialize deployment state: %v", err)
+		}
+		root.pipeline.OnResult(root.report.AddStage)
+
 		return nil
 	},
 	Run: func(cmd *cobra.Command, args []string) {
@@ -157,144 +236,57 @@ var rootCmd = &cobra.Command{
 		root.log.Debugf("Metadata data: %s", root.metadata.ToString())
 		root.log.Debugf("Flags: %s", root.FlagsToString())
 
-		err := root.config.Admin.InitializeSudo()
-		if err != nil {
-			root.log.Warn(fmt.Sprintf("Failed to authenticate sudo: %v", err))
-		}
+		stopWatchdog := root.startWatchdog(root.config.Timeouts.Watchdog)
 
-		// skip local also skips YAML configured accounts
-		// create local will trigger a manual account creation, but will not
-		if !root.SkipLocal || root.CreateLocal {
-			root.startAccountCreation(root.AdminStatus)
-		}
+		// stages were validated during the pre run.
+		results, err := root.pipeline.Run()
+		stopWatchdog()
 
-		// creating the files found in the install directories, it is flattened.
-		installDirectoryFiles := make([]string, 0)
-		for _, searchDir := range root.config.InstallDirectories {
-			searchFiles, err := utils.GetFiles(searchDir)
-			if err != nil {
-				root.log.Warn(fmt.Sprintf("Path %s does not exist, skipping path", searchDir))
-				continue
-			}
-
-			installDirectoryFiles = append(installDirectoryFiles, searchFiles...)
-		}
-
-		root.log.Debugf("File amount: %d | Directories: %v", len(installDirectoryFiles), root.config.InstallDirectories)
-
-		if len(installDirectoryFiles) < 1 {
-			srcPkgMsg := "No files found in search directories, packages will always be attempted to isntall"
-			root.log.Warn(srcPkgMsg)
-			fmt.Println(srcPkgMsg)
-		}
-
-		// NOTE: can make the pkg/dmg/app process efficient by searching once.
-		// something to note in the future if needed.
-
-		// automatically mount, extract, and dismount DMG files if they exist.
-		root.log.Info("Searching for DMG files")
-		dmgFiles, err := root.dep.filehandler.ReadDir(root.metadata.Files.DistDirectory, ".dmg")
-		if err != nil {
-			root.log.Warnf("Failed to search directory: %v", err)
-		} else {
-			// this requires the use of --include to install properly.
-			volumeMounts := root.dep.filehandler.AttachDmgs(dmgFiles)
-			if len(volumeMounts) > 0 {
-				root.dep.filehandler.AddDmgPackages(volumeMounts, root.metadata.Files.DistDirectory)
-				root.dep.filehandler.DetachDmgs(volumeMounts)
-			}
-		}
-
-		root.startPackageInstallation(root.dep.filehandler, installDirectoryFiles)
-
-		// app files will automatically get placed into the Applications folder
-		appFiles, err := root.dep.filehandler.ReadDir(root.metadata.Files.DistDirectory, ".app")
-		if err != nil {
-			root.log.Warn(fmt.Sprintf("Failed to search directory: %v", err))
-		}
-		if len(appFiles) > 0 {
-			applicationDir := "/Applications"
-			root.dep.filehandler.CopyFiles(appFiles, applicationDir)
+		if root.aborted() {
+			root.abort()
+			return
 		}
 
-		// mid deplyoment script execution
-		if len(root.config.Scripts.Mid) > 0 && !root.errors.ScriptsFailed {
-			fmt.Println("Executing mid-deployment scripts")
-			root.log.Debug(fmt.Sprintf("Mid-script files: %v", root.config.Scripts.Mid))
-
-			root.executeScripts(root.config.Scripts.Mid, root.data.scriptFiles)
+		root.exitCode |= exitCodeFromResults(results)
+		if len(root.dep.runner.TimedOut()) > 0 {
+			root.exitCode |= ExitTimeout
 		}
 
-		err = root.config.Admin.InitializeSudo()
+		// the deployment did not finish, the results of the stages that ran are in the report.
 		if err != nil {
-			root.log.Warn(fmt.Sprintf("Failed to authenticate sudo: %v", err))
-		}
-
-		request := requests.NewRequest(root.log)
-		// payload for sending to the server
-		// initialized with an empty key, Key gets updated below.
-		// used for sending the log over to the server.
-		currDate := time.Now().Format("2006-01-02")
-		serverLogFile := fmt.Sprintf("%s.%s.log", root.metadata.SerialTag, currDate)
-		logPayload := requests.NewLogPayload(serverLogFile)
-
-		if (!root.config.FileVault || !root.SkipFileVault) || root.ForceFileVault {
-			filevaultPayload := requests.NewFileVaultPayload("")
-			fvKey := root.startFileVault(root.dep.filevault, request)
-
-			root.log.Debugf("FileVault payload flag: %v", root.context.SkipFileVaultPayload)
-			if !root.context.SkipFileVaultPayload {
-				filevaultPayload.Key = fvKey
-				filevaultPayload.SetBody(root.metadata.SerialTag)
-
-				err := root.startRequest(filevaultPayload, request, root.config.ServerHost, "/api/fv")
-				if err != nil {
-					root.log.Warnf("Failed to send payload with FileVault key: %v", err)
-					root.warnFileVaultError(filevaultPayload)
-				}
-			} else {
-				root.log.Info("Skipped FileVault payload request")
-			}
-		}
-
-		// if admin is applied policies, it must be after all the sudo commands.
-		// unsure why, but from my testing it fails the filevault command when it was applied
-		// prior to running the command.
-		if root.config.Admin.ApplyPolicy {
-			policyString := root.config.Policy.BuildCommand()
-
-			root.applyPasswordPolicy(policyString, root.config.Admin.Username)
-		}
-
-		if !root.SkipLog {
-			root.log.Info("Sending log file to the server")
-
-			logPayload.Body = root.log.String()
-			err = root.startRequest(logPayload, request, root.config.ServerHost, "/api/log")
-			if err != nil {
-				root.log.Critical(fmt.Sprintf("Failed to send to data to server: %v", err))
-			}
+			root.log.Critical(fmt.Sprintf("Failed to run deployment stages: %v", err))
+			root.exitCode = ExitError
 		}
+		root.log.Infof("Deployment exit code: %d", root.exitCode)
 
-		// firewall must be last, all outbound connections are blocked upon activation.
-		// fun fact: i forgot i fixed this issue 4 months ago in a bash only script, and brought it back.
-		if root.config.Firewall {
-			root.startFirewall(root.dep.firewall)
-		}
+		root.report.ExitCode = int(root.exitCode)
+		root.updateReport()
+		root.report.Finish()
+		root.saveReport()
 	},
 	PostRun: func(cmd *cobra.Command, args []string) {
-		fmt.Printf("Completed deployment for %s\n", root.metadata.SerialTag)
-
-		// post script execution
-		if len(root.config.Scripts.Post) > 0 && !root.errors.ScriptsFailed {
-			fmt.Println("Executing post-deployment scripts")
-			root.log.Debug(fmt.Sprintf("Post-script files: %v", root.config.Scripts.Post))
-
-			root.executeScripts(root.config.Scripts.Post, root.data.scriptFiles)
+		// the files are needed to resume the deployment.
+		if root.aborted() {
+			return
 		}
 
+		fmt.Printf("Completed deployment for %s\n", root.metadata.SerialTag)
+
 		if root.Cleanup {
+			if root.dep.runner.DryRun() {
+				root.runHook(hookOnCleanup, "", root.config.Scripts.OnCleanup)
+				root.dep.runner.Planf("remove %s and %s", root.metadata.Files.ZipFile, root.metadata.Files.DistDirectory)
+				return
+			}
+
 			if root.errors.ServerFailed || root.config.Cleanup == "warn" {
+				// no confirmation can be given, the files are kept.
+				if !prompt.Interactive() {
+					root.log.Warn("Skipped cleanup, confirmation is required in non-interactive mode")
+					fmt.Println("Deployment files were not removed, confirmation is required")
+					return
+				}
+
 				choice := ""
 				validChoices := "yn"
 
@@ -331,6 +323,9 @@ var rootCmd = &cobra.Command{
 				}
 			}
 
+			// the scripts are in the deployment files, they run before the files are removed.
+			root.runHook(hookOnCleanup, "", root.config.Scripts.OnCleanup)
+
 			filesToRemove := []string{root.metadata.Files.ZipFile, root.metadata.Files.DistDirectory}
 
 			fmt.Println("Cleaning deployment files...")
@@ -340,9 +335,29 @@ var rootCmd = &cobra.Command{
 }
 
 func Execute() {
-	if err := rootCmd.Execute(); err != nil {
+	err := rootCmd.Execute()
+
+	if root.stopInterrupts != nil {
+		root.stopInterrupts()
+	}
+	if root.dep.sudo != nil {
+		root.dep.sudo.Stop()
+	}
+
+	if err != nil {
 		fmt.Fprintln(os.Stderr, err)
-		os.Exit(1)
+
+		code := ExitError
+		var exitErr *exitError
+		if errors.As(err, &exitErr) {
+			code = exitErr.code
+		}
+
+		os.Exit(int(code))
+	}
+
+	if root.exitCode != ExitSuccess {
+		os.Exit(int(root.exitCode))
 	}
 }
 
@@ -352,6 +367,8 @@ func InitializeRoot() {
 		"exclude", []string{}, "Exclude a package from installing")
 	rootCmd.Flags().StringArrayVar(&root.IncludePackages,
 		"include", []string{}, "Include a package to install")
+	rootCmd.Flags().StringArrayVar(&root.SkipStages,
+		"skip", []string{}, "Skip a stage of the deployment")
 	rootCmd.Flags().StringVar(
 		&root.PlistPath, "plist", "", "Apply password policies with a plist")
 
@@ -363,6 +380,8 @@ func InitializeRoot() {
 		&root.Verbose, "verbose", "v", false, "Displays the info output to the terminal")
 	rootCmd.Flags().BoolVar(
 		&root.Debug, "debug", false, "Displays the debug output to the terminal")
+	rootCmd.Flags().BoolVar(
+		&root.Resume, "resume", false, "Resume the deployment from the last completed stage")
 	rootCmd.Flags().BoolVar(
 		&root.SkipLog, "skiplog", false, "Skip sending the logs to the server")
 	rootCmd.Flags().BoolVar(
@@ -376,6 +395,21 @@ func InitializeRoot() {
 		&root.ForceFileVault, "forcefilevault", false, "Forces the FileVault process and overwrites existing keys",
 	)
 
+	// dry run applies to the root and all sub commands.
+	rootCmd.PersistentFlags().BoolVar(
+		&root.DryRun, "dryrun", false, "Print the deployment actions without running them")
+	rootCmd.PersistentFlags().BoolVar(
+		&root.NonInteractive, "noninteractive", false, "Disable all prompts, missing inputs are errors")
+	rootCmd.PersistentFlags().StringVar(
+		&root.ConfigPath, "config", "", "Use a YAML config file instead of the embedded config")
+	rootCmd.PersistentFlags().StringVar(
+		&root.Profile, "profile", "", "Apply a profile of the YAML config")
+	cobra.OnInitialize(func() {
+		root.dep.runner = runner.New()
+		root.dep.runner.SetDryRun(root.DryRun)
+		prompt.SetInteractive(!root.NonInteractive)
+	})
+
 	rootCmd.MarkFlagsMutuallyExclusive("skiplocal", "createlocal")
 	rootCmd.MarkFlagsMutuallyExclusive("debug", "verbose")
 	rootCmd.MarkFlagsMutuallyExclusive("skipfilevault", "forcefilevault")
@@ -383,7 +417,9 @@ func InitializeRoot() {
 
 // startAccountCreation starts the account making process.
 // This is used for the YAML accounts and single use accounts.
-func (r *RootData) startAccountCreation(adminStatus bool) {
+//
+// An error is returned if any account failed to be created.
+func (r *RootData) startAccountCreation(adminStatus bool) error {
 	fmt.Println("Starting account creation")
 
 	r.log.Debugf("YAML accounts amount: %v", len(r.config.Accounts))
@@ -392,29 +428,45 @@ func (r *RootData) startAccountCreation(adminStatus bool) {
 	// this takes precedent over the r.config.Accounts.
 	if r.CreateLocal {
 		account := yaml.UserInfo{}
+		account.SetFromEnv(envLocalUsername, envLocalPassword)
 
-		accountName := r.accountCreation(&account, adminStatus)
+		accountName, err := r.accountCreation(&account, adminStatus)
+		if err != nil {
+			return err
+		}
 		if accountName != "" {
-			r.postAccountCreation(accountName, account.Password, r.config.Policy.ChangeOnLogin)
+			return r.postAccountCreation(accountName, account.Password, r.config.Policy.ChangeOnLogin)
 		}
 
-		return
+		return nil
 	}
 
+	accountErrors := []error{}
+
 	for key := range r.config.Accounts {
 		currAccount := r.config.Accounts[key]
 
-		accountName := r.accountCreation(&currAccount, adminStatus)
+		accountName, err := r.accountCreation(&currAccount, adminStatus)
+		if err != nil {
+			accountErrors = append(accountErrors, err)
+			continue
+		}
 		if accountName != "" {
-			r.postAccountCreation(accountName, currAccount.Password, currAccount.ApplyPolicy)
+			err = r.postAccountCreation(accountName, currAccount.Password, currAccount.ApplyPolicy)
+			if err != nil {
+				accountErrors = append(accountErrors, err)
+			}
 		}
 	}
+
+	return errors.Join(accountErrors...)
 }
 
 // accountCreation starts the account creation process.
 //
 // It returns the internal username if successful, otherwise it will return an empty string.
-func (r *RootData) accountCreation(currAccount *yaml.UserInfo, adminStatus bool) string {
+// An error is returned if the account failed to be created, a skipped account is not an error.
+func (r *RootData) accountCreation(currAccount *yaml.UserInfo, adminStatus bool) (string, error) {
 	accountName, err := r.dep.usermaker.CreateAccount(currAccount, adminStatus)
 	if err != nil {
 		// if user creation is skipped then dont log the error
@@ -423,16 +475,20 @@ func (r *RootData) accountCreation(currAccount *yaml.UserInfo, adminStatus bool)
 			r.log.Warn(logMsg)
 
 			fmt.Println("Failed to create account")
+
+			return "", err
 		}
 
-		return ""
+		return "", nil
 	}
 
-	return accountName
+	return accountName, nil
 }
 
 // postAccountCreation applies the post account creation policies and secure token.
-func (r *RootData) postAccountCreation(accountName string, accountPassword string, applyPolicy bool) {
+//
+// An error is returned if the secure token failed to apply to the account.
+func (r *RootData) postAccountCreation(accountName string, accountPassword string, applyPolicy bool) error {
 	fmt.Println("Applying post-account creation workflow")
 
 	err := r.dep.filevault.AddSecureToken(accountName, accountPassword)
@@ -455,9 +511,9 @@ func (r *RootData) postAccountCreation(accountName string, accountPassword strin
 		err = r.dep.usermaker.DeleteAccount(accountName)
 		if err != nil {
 			r.log.Warn(fmt.Sprintf("Failed to run user removal command, manual deletion needed: %v", err))
-
-			return
 		}
+
+		return fmt.Errorf("failed to add secure token to %s", accountName)
 	}
 
 	if applyPolicy {
@@ -466,7 +522,10 @@ func (r *RootData) postAccountCreation(accountName string, accountPassword strin
 		r.applyPasswordPolicy(policyString, accountName)
 	}
 
+	r.journal.AddAccounts(accountName)
 	fmt.Printf("User %s successfully created\n", accountName)
+
+	return nil
 }
 
 // startPackageInstallation begins the package installation process.
@@ -474,7 +533,9 @@ func (r *RootData) postAccountCreation(accountName string, accountPassword strin
 // handler is the FileHandler.
 //
 // installDirectoryFiles is a slice of strings that contain the files of installation directories.
-func (r *RootData) startPackageInstallation(handler *core.FileHandler, installDirectoryFiles []string) {
+//
+// An error is returned if the packages could not be installed or if any package failed to install.
+func (r *RootData) startPackageInstallation(handler *core.FileHandler, installDirectoryFiles []string) error {
 	fmt.Println("Starting application installation")
 	// must be ran prior to installing software, if this fails then
 	// software will not install.
@@ -483,7 +544,7 @@ func (r *RootData) startPackageInstallation(handler *core.FileHandler, installDi
 		r.log.Warn(fmt.Sprintf("Failed to install Rosetta: %v\n", err))
 		fmt.Println("Rosetta failed to install, please try again or run 'macdeploy install <file>...'")
 
-		return
+		return err
 	}
 
 	// removing packages take precedent.
@@ -494,20 +555,32 @@ func (r *RootData) startPackageInstallation(handler *core.FileHandler, installDi
 	packages, err := handler.ReadDir(r.metadata.Files.DistDirectory, ".pkg")
 	if err != nil {
 		r.log.Warnf("Issue occurred with searching directory %s: %v", r.metadata.Files.DistDirectory, err)
-		return
+		return err
 	}
 
 	if len(packages) < 1 {
 		r.log.Warnf("Packages found in %s: %d", r.metadata.Files.DistDirectory, len(packages))
 		fmt.Println("No packages found in 'dist', skipping package installation")
-		return
+		return nil
 	}
 
 	installCount := handler.InstallPackages(packages, installDirectoryFiles)
-	msg := fmt.Sprintf("Installed %d/%d files", installCount, len(handler.GetPackages()))
+	r.journal.AddPackages(handler.GetInstalledPackages()...)
+	conditionSkipped := len(handler.GetConditionSkippedPackages())
+	msg := fmt.Sprintf("Installed %d/%d files", installCount, len(handler.GetPackages())-conditionSkipped)
+	if conditionSkipped > 0 {
+		msg = fmt.Sprintf("%s, %d skipped by their conditions", msg, conditionSkipped)
+	}
 
 	r.log.Debug(msg)
 	fmt.Println(msg)
+
+	failedPackages := handler.GetFailedPackages()
+	if len(failedPackages) > 0 {
+		return fmt.Errorf("failed to install packages: %v", failedPackages)
+	}
+
+	return nil
 }
 
 // startFileVault begins the FileVault process and returns the generated key.
@@ -623,7 +696,10 @@ func (r *RootData) startFileVault(filevault *core.FileVault, request *requests.R
 	return fvKey
 }
 
-func (r *RootData) startFirewall(firewall *core.Firewall) {
+// startFirewall enables the Firewall if it is not already enabled.
+//
+// An error is returned if the Firewall failed to enable.
+func (r *RootData) startFirewall(firewall *core.Firewall) error {
 	fmt.Println("Starting Firewall process")
 	fwStatus, err := firewall.Status()
 	if err != nil {
@@ -637,10 +713,24 @@ func (r *RootData) startFirewall(firewall *core.Firewall) {
 		err = firewall.Enable()
 		if err != nil {
 			r.log.Warn(err.Error())
+			return err
 		}
 	} else {
 		r.log.Info("Firewall is already enabled")
 	}
+
+	return nil
+}
+
+// newRequest creates a Request to the server. The requests are canceled with the deployment,
+// except for the requests of an aborted deployment, which send its report and log.
+func (r *RootData) newRequest() *requests.Request {
+	ctx := r.ctx
+	if ctx == nil || ctx.Err() != nil {
+		ctx = context.Background()
+	}
+
+	return requests.NewRequest(ctx, r.log, r.dep.runner)
 }
 
 // startRequest sends the payload to the server.
@@ -692,10 +782,10 @@ func (r *RootData) applyPasswordPolicy(policyString string, username string) {
 	// if a plist is given, it takes precendent over the policies defined in the config
 	if r.PlistPath == "" {
 		r.log.Debug(fmt.Sprintf("Policy string: %s | User: %s", policyString, username))
-		out, err = root.config.Policy.SetPolicy(policyString, username)
+		out, err = root.config.Policy.SetPolicy(r.dep.runner, policyString, username)
 	} else {
 		r.log.Debug(fmt.Sprintf("plist path: %s | User: %s", r.PlistPath, username))
-		out, err = root.config.Policy.SetPolicyPlist(r.PlistPath, username)
+		out, err = root.config.Policy.SetPolicyPlist(r.dep.runner, r.PlistPath, username)
 	}
 	if err != nil {
 		r.log.Warn(fmt.Sprintf("Failed to add policy to user %s: %v", username, err))
@@ -714,32 +804,94 @@ func (r *RootData) applyPasswordPolicy(policyString string, username string) {
 	}
 }
 
-func (r *RootData) executeScripts(executingScripts []string, scriptPaths []string) {
-	for _, scriptFile := range executingScripts {
+// executeScripts runs the scripts of the hook found in the script paths. The scripts are given
+// their arguments from the config and the context of the deployment in the environment.
+//
+// If a required script fails, the deployment is aborted and the scripts after it do not run.
+// An error is returned if any script failed to run.
+func (r *RootData) executeScripts(stage string, hook string, scripts []yaml.Script, scriptPaths []string) error {
+	scriptErrors := []error{}
+	env := r.scriptEnv(stage, hook)
+
+	for _, script := range scripts {
+		scriptFile := script.Name
 		if scriptFile == "" {
 			continue
 		}
 
 		fmt.Printf("Running script: %s\n", scriptFile)
-		out, err := r.dep.filehandler.ExecuteScript(scriptFile, scriptPaths)
-		scriptOutMsg := fmt.Sprintf("Script %s output: %s", scriptFile, out)
+		out, err := r.runScript(script, scriptPaths, env)
+		r.report.AddScript(scriptFile, out.Stdout, out.Stderr, err)
+
+		// the output is streamed to the terminal with the verbose output.
+		streamed := r.Verbose || r.Debug
 		if err != nil {
 			r.log.Warn(fmt.Sprintf("Failed to run %s: %v", scriptFile, err))
-			r.log.Info(scriptOutMsg)
 
 			fmt.Printf("Script %s failed to run\n", scriptFile)
-			if out != "" {
-				fmt.Println(scriptOutMsg)
+			if out.Stderr != "" && !streamed {
+				fmt.Printf("Script %s error output: %s\n", scriptFile, out.Stderr)
+			}
+
+			scriptErrors = append(scriptErrors, fmt.Errorf("script %s failed: %v", scriptFile, err))
+
+			if script.Required {
+				r.log.Critical(fmt.Sprintf("Required script %s failed, aborting the deployment", scriptFile))
+				fmt.Printf("Required script %s failed, aborting the deployment\n", scriptFile)
+
+				r.cancel(fmt.Errorf("required script %s failed", scriptFile))
+				break
 			}
 
 			continue
 		}
 
-		if out != "" {
-			r.log.Info(scriptOutMsg)
-			fmt.Println(scriptOutMsg)
+		if out.Stdout != "" && !streamed {
+			fmt.Printf("Script %s output: %s\n", scriptFile, out.Stdout)
 		}
 	}
+
+	return errors.Join(scriptErrors...)
+}
+
+// runScript runs the script with its options from the config.
+//
+// An error is returned if the script failed, or if it runs as a user that was not
+// created by the deployment.
+func (r *RootData) runScript(script yaml.Script, scriptPaths []string, env []string) (core.ScriptOutput, error) {
+	if script.RunAs != "" && !script.RunAsRoot() && !r.dep.runner.DryRun() &&
+		(r.journal == nil || !slices.Contains(r.journal.AccountsCreated, script.RunAs)) {
+		return core.ScriptOutput{}, fmt.Errorf("run_as user %s was not created by the deployment", script.RunAs)
+	}
+
+	opts := core.ScriptOptions{
+		Args:      script.Args,
+		Env:       env,
+		Timeout:   script.Timeout,
+		RunAs:     script.RunAs,
+		ExitCodes: script.ExitCodes,
+	}
+	if len(opts.Args) == 0 {
+		opts.Args = r.config.Scripts.Args[script.Name]
+	}
+
+	if script.IsInline() {
+		return r.dep.filehandler.ExecuteInlineScript(script.Name, script.Inline, opts)
+	}
+
+	return r.dep.filehandler.ExecuteScript(script.Name, scriptPaths, opts)
+}
+
+// runnableScripts returns the scripts that can run. The script files cannot run if the
+// search for the script files has failed, the inline scripts are always ran.
+func (r *RootData) runnableScripts(scripts []yaml.Script) []yaml.Script {
+	if !r.errors.ScriptsFailed {
+		return scripts
+	}
+
+	return slices.DeleteFunc(slices.Clone(scripts), func(script yaml.Script) bool {
+		return !script.IsInline()
+	})
 }
 
 // warnFileVaultError is used to warn the user on the terminal that FileVault has failed.
@@ -785,7 +937,7 @@ func (r *RootData) warnFileVaultError(filevaultPayload *requests.FileVaultPayloa
 // the hook lifecycle injection, and some terminal printing if true.
 func (r *RootData) initialize(isSubCommand bool) {
 	// not exiting, just in case mac fails somehow. but there are checks for non-mac devices.
-	serialTag, err := utils.GetSerialTag()
+	serialTag, err := utils.GetSerialTag(r.dep.runner)
 	if err != nil {
 		serialTag = "UNKNOWN"
 		if !isSubCommand {
@@ -805,40 +957,6 @@ func (r *RootData) initialize(isSubCommand bool) {
 
 	metadata := utils.NewMetadata(serialTag, currDir+"/"+distDirectory, currDir+"/"+zipFile)
 	scripts := scripts.NewScript()
-	config, err := yaml.NewConfig(embedhandler.YAMLBytes)
-	if err != nil {
-		// TODO: make this a better error message (incorrect keys, required keys missing, etc)
-		fmt.Printf("Error parsing YAML configuration, %v\n", err)
-		os.Exit(1)
-	}
-
-	validateErr := yaml.Validate(config)
-	if validateErr != nil {
-		fmt.Println(validateErr)
-		os.Exit(1)
-	}
-
-	// checking if admin info was given or not
-	if config.Admin.Username == "" {
-		err = config.Admin.SetUsername()
-		if err != nil {
-			fmt.Printf("Failed to get username of admin: %v\n", err)
-			os.Exit(1)
-		}
-	}
-	if config.Admin.Password == "" {
-		fmt.Println("No admin password given")
-		err = config.Admin.SetPassword(false)
-		if err != nil {
-			fmt.Println(err)
-			os.Exit(1)
-		}
-	}
-
-	err = config.Admin.InitializeSudo()
-	if err != nil {
-		fmt.Printf("Failed to initialize sudo with given password: %v\n", err)
-	}
 
 	defaultLogDir := fmt.Sprintf("%s/%s", metadata.Home, defaultLogDir)
 
@@ -869,17 +987,89 @@ func (r *RootData) initialize(isSubCommand bool) {
 	}
 
 	log := logger.NewLogger(baseLog, logLevel)
+	r.log = log
+	// the secrets registered with the logger are also masked in the commands and their outputs.
+	r.dep.runner.SetRedactor(log)
+
+	config, err := r.loadConfig(serialTag, isSubCommand)
+	if err != nil {
+		// TODO: make this a better error message (incorrect keys, required keys missing, etc)
+		fmt.Println(err)
+		os.Exit(int(ExitConfig))
+	}
+
+	validateErr := yaml.Validate(config)
+	if validateErr != nil {
+		fmt.Printf("Config %s failed to validate:\n", r.configSource)
+		fmt.Println(validateErr)
+		os.Exit(int(ExitConfig))
+	}
+
+	// the missing inputs stop the binary before the sudo session and the scripts start.
+	if !isSubCommand {
+		err = r.checkInputs(config)
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(int(ExitError))
+		}
+	}
+
+	config.Admin.SetFromEnv(envAdminUsername, envAdminPassword)
+
+	// checking if admin info was given or not
+	if config.Admin.Username == "" {
+		err = config.Admin.SetUsername(r.dep.runner)
+		if err != nil {
+			fmt.Printf("Failed to get username of admin: %v\n", err)
+			os.Exit(1)
+		}
+	}
+	if config.Admin.Password == "" {
+		err = prompt.Check("admin password", "admin.password in the config", envAdminPassword)
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(int(ExitError))
+		}
+
+		fmt.Println("No admin password given")
+		err = config.Admin.SetPassword(r.dep.runner, false)
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+	}
+
+	// the passwords are resolved, they are masked in the logs, the commands and the report.
+	log.AddSecrets(config.Admin.Password)
+	for _, account := range config.Accounts {
+		log.AddSecrets(account.Password)
+	}
+
+	// the sudo session is kept alive until the binary exits, it is not stopped with a stage.
+	sudo := core.NewSudoSession(config.Admin, log, r.dep.runner.WithContext(context.Background()))
+	err = sudo.Start()
+	if err != nil {
+		fmt.Printf("Failed to initialize sudo with given password: %v\n", err)
+	}
 
 	// dependency initializations
-	filevault := core.NewFileVault(config.Admin, scripts, log)
-	user := core.NewUser(config.Admin, scripts, log)
-	handler := core.NewFileHandler(log)
-	firewall := core.NewFirewall(log, scripts)
+	filevault := core.NewFileVault(config.Admin, scripts, log, r.dep.runner)
+	user := core.NewUser(config.Admin, scripts, log, r.dep.runner)
+	handler := core.NewFileHandler(log, r.dep.runner)
+	firewall := core.NewFirewall(log, scripts, r.dep.runner)
 
 	handler.AddMapPackages(config.Packages)
+	handler.SetChecksums(config.Checksums)
+	handler.SetAppDirectories(config.InstallDirectories)
+	handler.SetDevice(utils.GetDevice(r.dep.runner))
 
-	r.log = log
 	r.config = config
+	r.dep.runner.SetTimeout(config.Timeouts.Command)
+
+	r.log.Infof("Using config %s | Device config from server: %t", r.configSource, r.configOverlay)
+	if r.Profile != "" {
+		r.log.Infof("Using config profile %s", r.Profile)
+	}
 	r.script = scripts
 	r.metadata = metadata
 
@@ -887,28 +1077,67 @@ func (r *RootData) initialize(isSubCommand bool) {
 	r.dep.filehandler = handler
 	r.dep.firewall = firewall
 	r.dep.filevault = filevault
+	r.dep.sudo = sudo
 
 	// script hooks, this is not applicable to sub commands.
 	if !isSubCommand {
+		r.runID = newRunID()
+		r.log.Infof("Deployment run ID: %s", r.runID)
+
+		r.report = report.NewReport(serialTag, r.dep.runner.DryRun())
+		r.report.SetRedactor(log)
+		r.report.Profile = r.Profile
+		r.report.RunID = r.runID
+
 		// initialized for the lifecycle during pre, install, and post script stages
 		scriptFiles, err := r.dep.filehandler.ReadDir(root.metadata.Files.DistDirectory, ".sh")
 		if err != nil {
 			r.log.Warn(fmt.Sprintf("Failed to find script files: %v", err))
-			fmt.Println("Scripts will not be ran during the deployment")
+			fmt.Println("Script files will not be ran during the deployment")
 
 			r.errors.ScriptsFailed = true
 		}
 
 		r.data.scriptFiles = scriptFiles
 
-		// pre script execution
-		if len(r.config.Scripts.Pre) > 0 && !root.errors.ScriptsFailed {
-			fmt.Println("Executing pre-deployment scripts")
-			r.log.Debug(fmt.Sprintf("Pre-script files: %v", root.config.Scripts.Pre))
+		r.stopInterrupts = r.handleInterrupts()
+	}
+}
 
-			r.executeScripts(root.config.Scripts.Pre, root.data.scriptFiles)
+// checkInputs checks that the inputs of the deployment can be given without a prompt
+// if non-interactive mode is enabled. This prevents the deployment from stopping at
+// a prompt after it has started.
+//
+// An error is returned if an input is missing.
+func (r *RootData) checkInputs(config *yaml.Config) error {
+	if prompt.Interactive() || r.dep.runner.DryRun() {
+		return nil
+	}
+	if r.SkipLocal || slices.Contains(r.SkipStages, stageAccounts) {
+		return nil
+	}
+
+	if r.CreateLocal {
+		if os.Getenv(envLocalUsername) == "" {
+			return prompt.Check("local account username", envLocalUsername)
+		}
+		if os.Getenv(envLocalPassword) == "" {
+			return prompt.Check("local account password", envLocalPassword)
+		}
+
+		return nil
+	}
+
+	for key, account := range config.Accounts {
+		if account.Username == "" {
+			return prompt.Check("account username", fmt.Sprintf("accounts.%s.username in the config", key))
+		}
+		if account.Password == "" {
+			return prompt.Check("account password", fmt.Sprintf("accounts.%s.password in the config", key))
 		}
 	}
+
+	return nil
 }
 
 // FlagsToString returns the string representation of
@@ -922,7 +1151,13 @@ func (r *RootData) FlagsToString() string {
 
 	slice = append(slice, format("exclude", r.ExcludePackages))
 	slice = append(slice, format("include", r.IncludePackages))
+	slice = append(slice, format("skip", r.SkipStages))
 	slice = append(slice, format("plist", r.PlistPath))
+	slice = append(slice, format("config", r.ConfigPath))
+	slice = append(slice, format("profile", r.Profile))
+	slice = append(slice, format("dryrun", r.DryRun))
+	slice = append(slice, format("noninteractive", r.NonInteractive))
+	slice = append(slice, format("resume", r.Resume))
 	slice = append(slice, format("admin", r.AdminStatus))
 	slice = append(slice, format("cleanup", r.Cleanup))
 	slice = append(slice, format("verbose", r.Verbose))
@@ -0,0 +1,114 @@
+package cmd
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bobllor/macdeploy/src/deploy-files/yaml"
+)
+
+// environment variables given to the scripts with the context of the deployment.
+const (
+	envScriptSerialTag   string = "MACDEPLOY_SERIAL"
+	envScriptStage       string = "MACDEPLOY_STAGE"
+	envScriptHook        string = "MACDEPLOY_HOOK"
+	envScriptDist        string = "MACDEPLOY_DIST_DIR"
+	envScriptAccounts    string = "MACDEPLOY_ACCOUNTS"
+	envScriptServerHost  string = "MACDEPLOY_SERVER_HOST"
+	envScriptFileVault   string = "MACDEPLOY_FILEVAULT"
+	envScriptKeyEscrowed string = "MACDEPLOY_FILEVAULT_KEY_ESCROWED"
+	envScriptProfile     string = "MACDEPLOY_PROFILE"
+	envScriptRunID       string = "MACDEPLOY_RUN_ID"
+	envScriptDryRun      string = "MACDEPLOY_DRYRUN"
+)
+
+// hook points of the scripts, the names are the fields of the scripts in the config.
+const (
+	hookPre           string = "pre"
+	hookMid           string = "mid"
+	hookPost          string = "post"
+	hookPostAccounts  string = "post_accounts"
+	hookPreFileVault  string = "pre_filevault"
+	hookPostFileVault string = "post_filevault"
+	hookPreFirewall   string = "pre_firewall"
+	hookOnFailure     string = "on_failure"
+	hookOnCleanup     string = "on_cleanup"
+)
+
+// newRunID returns a unique ID of the deployment run, it starts with the start time.
+func newRunID() string {
+	buf := make([]byte, 4)
+	// rand.Read never returns an error.
+	rand.Read(buf)
+
+	return fmt.Sprintf("%s-%s", time.Now().Format("20060102T150405"), hex.EncodeToString(buf))
+}
+
+// scriptEnv returns the environment of the scripts ran by the hook during the stage. It is the
+// environment of the binary with the context of the deployment added. The hook is empty for
+// the scripts of a stage from the config.
+//
+// The password environment variables are removed, the scripts never receive the passwords.
+func (r *RootData) scriptEnv(stage string, hook string) []string {
+	env := slices.DeleteFunc(os.Environ(), func(v string) bool {
+		return strings.HasPrefix(v, envAdminPassword+"=") || strings.HasPrefix(v, envLocalPassword+"=")
+	})
+
+	// the journal is not created outside of the deployment.
+	accounts := []string{}
+	keyEscrowed := false
+	if r.journal != nil {
+		accounts = r.journal.AccountsCreated
+		keyEscrowed = r.journal.KeyEscrowed
+	}
+
+	fvStatus, err := r.dep.filevault.Status()
+	if err != nil {
+		r.log.Warnf("Failed to check FileVault status for the scripts: %v", err)
+	}
+
+	vars := map[string]string{
+		envScriptSerialTag:   r.metadata.SerialTag,
+		envScriptStage:       stage,
+		envScriptHook:        hook,
+		envScriptDist:        r.metadata.Files.DistDirectory,
+		envScriptAccounts:    strings.Join(accounts, ","),
+		envScriptServerHost:  r.config.ServerHost,
+		envScriptFileVault:   strconv.FormatBool(fvStatus),
+		envScriptKeyEscrowed: strconv.FormatBool(keyEscrowed),
+		envScriptProfile:     r.Profile,
+		envScriptRunID:       r.runID,
+		envScriptDryRun:      strconv.FormatBool(r.DryRun),
+	}
+
+	for key, value := range vars {
+		env = append(env, fmt.Sprintf("%s=%s", key, value))
+	}
+
+	r.log.Debugf("Script environment for stage %s and hook %s: %v", stage, hook, vars)
+
+	return env
+}
+
+// runHook runs the scripts of a hook point for the stage. A failed script adds
+// the scripts exit code, the stage is not failed.
+func (r *RootData) runHook(hook string, stage string, scripts []yaml.Script) {
+	scripts = r.runnableScripts(scripts)
+	if len(scripts) == 0 {
+		return
+	}
+
+	fmt.Printf("Executing %s scripts\n", hook)
+	r.log.Debug(fmt.Sprintf("Hook %s script files: %v", hook, yaml.ScriptNames(scripts)))
+
+	err := r.executeScripts(stage, hook, scripts, r.data.scriptFiles)
+	if err != nil {
+		r.exitCode |= ExitScripts
+	}
+}
@@ -0,0 +1,404 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"slices"
+	"time"
+
+	"github.com/bobllor/macdeploy/src/deploy-files/pipeline"
+	requests "github.com/bobllor/macdeploy/src/deploy-files/server-requests"
+	"github.com/bobllor/macdeploy/src/deploy-files/utils"
+	"github.com/bobllor/macdeploy/src/deploy-files/yaml"
+)
+
+// stage names of the default deployment process.
+const (
+	stagePreScripts  string = "pre_scripts"
+	stageAccounts    string = "accounts"
+	stageDmg         string = "dmg"
+	stagePackages    string = "packages"
+	stageApps        string = "apps"
+	stageMidScripts  string = "mid_scripts"
+	stageFileVault   string = "filevault"
+	stagePolicy      string = "policy"
+	stageLog         string = "log"
+	stageReport      string = "report"
+	stageFirewall    string = "firewall"
+	stagePostScripts string = "post_scripts"
+)
+
+// newPipeline creates the deployment pipeline with the default stages, then applies
+// the stages from the config and the skip flags.
+//
+// An error is returned if the configured stages are invalid.
+func (r *RootData) newPipeline() (*pipeline.Pipeline, error) {
+	p := pipeline.NewPipeline(r.log)
+
+	defaultStages := []pipeline.Stage{
+		{
+			Name: stagePreScripts,
+			Enabled: func() bool {
+				return len(r.runnableScripts(r.config.Scripts.Pre)) > 0
+			},
+			Run: func() error {
+				preScripts := r.runnableScripts(r.config.Scripts.Pre)
+
+				fmt.Println("Executing pre-deployment scripts")
+				r.log.Debug(fmt.Sprintf("Pre-script files: %v", yaml.ScriptNames(preScripts)))
+
+				return r.executeScripts(stagePreScripts, hookPre, preScripts, r.data.scriptFiles)
+			},
+		},
+		{
+			Name: stageAccounts,
+			Run: func() error {
+				return r.startAccountCreation(r.AdminStatus)
+			},
+		},
+		{
+			Name: stageDmg,
+			Run:  r.runDmgStage,
+		},
+		{
+			Name: stagePackages,
+			Run: func() error {
+				return r.startPackageInstallation(r.dep.filehandler, r.getInstallDirectoryFiles())
+			},
+		},
+		{
+			Name: stageApps,
+			Run:  r.runAppsStage,
+		},
+		{
+			Name: stageMidScripts,
+			Enabled: func() bool {
+				return len(r.runnableScripts(r.config.Scripts.Mid)) > 0
+			},
+			Run: func() error {
+				midScripts := r.runnableScripts(r.config.Scripts.Mid)
+
+				fmt.Println("Executing mid-deployment scripts")
+				r.log.Debug(fmt.Sprintf("Mid-script files: %v", yaml.ScriptNames(midScripts)))
+
+				return r.executeScripts(stageMidScripts, hookMid, midScripts, r.data.scriptFiles)
+			},
+		},
+		{
+			Name: stageFileVault,
+			Enabled: func() bool {
+				return r.config.FileVault || r.ForceFileVault
+			},
+			Run: r.runFileVaultStage,
+		},
+		{
+			// if admin is applied policies, it must be after all the sudo commands.
+			// unsure why, but from my testing it fails the filevault command when it was applied
+			// prior to running the command.
+			Name:      stagePolicy,
+			DependsOn: []string{stageFileVault},
+			Enabled: func() bool {
+				return r.config.Admin.ApplyPolicy
+			},
+			Run: func() error {
+				policyString := r.config.Policy.BuildCommand()
+
+				r.applyPasswordPolicy(policyString, r.config.Admin.Username)
+				return nil
+			},
+		},
+		{
+			Name: stageLog,
+			Run:  r.runLogStage,
+		},
+		{
+			Name: stageReport,
+			Run:  r.runReportStage,
+		},
+		{
+			// firewall must be last, all outbound connections are blocked upon activation.
+			// fun fact: i forgot i fixed this issue 4 months ago in a bash only script, and brought it back.
+			Name:      stageFirewall,
+			DependsOn: []string{stageLog, stageReport},
+			Enabled: func() bool {
+				return r.config.Firewall
+			},
+			Run: func() error {
+				return r.startFirewall(r.dep.firewall)
+			},
+		},
+		{
+			Name: stagePostScripts,
+			Enabled: func() bool {
+				return len(r.runnableScripts(r.config.Scripts.Post)) > 0
+			},
+			Run: func() error {
+				postScripts := r.runnableScripts(r.config.Scripts.Post)
+
+				fmt.Println("Executing post-deployment scripts")
+				r.log.Debug(fmt.Sprintf("Post-script files: %v", yaml.ScriptNames(postScripts)))
+
+				return r.executeScripts(stagePostScripts, hookPost, postScripts, r.data.scriptFiles)
+			},
+		},
+	}
+
+	for _, stage := range defaultStages {
+		err := p.Register(stage)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if len(r.config.Stages) > 0 {
+		order := []string{}
+
+		// the pre scripts run first unless the config places or disables them.
+		listed := slices.ContainsFunc(r.config.Stages, func(stageConfig yaml.StageConfig) bool {
+			return stageConfig.Name == stagePreScripts
+		})
+		if !listed {
+			order = append(order, stagePreScripts)
+		}
+
+		for _, stageConfig := range r.config.Stages {
+			if !slices.Contains(p.Stages(), stageConfig.Name) {
+				// new stages can only run scripts
+				if len(stageConfig.Scripts) == 0 {
+					return nil, fmt.Errorf("stage %s is not a default stage and has no scripts", stageConfig.Name)
+				}
+
+				stageName := stageConfig.Name
+				scriptFiles := stageConfig.Scripts
+				err := p.Register(pipeline.Stage{
+					Name: stageConfig.Name,
+					Enabled: func() bool {
+						return len(r.runnableScripts(scriptFiles)) > 0
+					},
+					Run: func() error {
+						stageScripts := r.runnableScripts(scriptFiles)
+						r.log.Debug(fmt.Sprintf("Stage script files: %v", yaml.ScriptNames(stageScripts)))
+
+						return r.executeScripts(stageName, "", stageScripts, r.data.scriptFiles)
+					},
+				})
+				if err != nil {
+					return nil, err
+				}
+			}
+
+			err := p.AddDependencies(stageConfig.Name, stageConfig.DependsOn...)
+			if err != nil {
+				return nil, err
+			}
+
+			if !stageConfig.Disabled {
+				order = append(order, stageConfig.Name)
+			}
+		}
+
+		err := p.SetOrder(order)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// the older flags are kept for compatibility with --skip.
+	skipStages := slices.Clone(r.SkipStages)
+	if r.SkipLocal {
+		skipStages = append(skipStages, stageAccounts)
+	}
+	if r.SkipFileVault {
+		skipStages = append(skipStages, stageFileVault)
+	}
+	if r.SkipLog {
+		skipStages = append(skipStages, stageLog)
+	}
+
+	err := p.Skip(skipStages...)
+	if err != nil {
+		return nil, err
+	}
+
+	for name, timeout := range r.config.Timeouts.Stages {
+		err := p.SetTimeout(name, timeout)
+		if err != nil {
+			return nil, fmt.Errorf("invalid timeout: %v", err)
+		}
+	}
+
+	// the commands of a stage are stopped when the stage reaches its time limit.
+	p.OnStart(func(ctx context.Context, name string) {
+		r.dep.runner.SetContext(ctx)
+	})
+	p.SetContext(r.ctx)
+	p.OnResult(func(result pipeline.Result) {
+		r.dep.runner.SetContext(r.ctx)
+	})
+
+	// the hook scripts run with the context of the stage, before the results are recorded.
+	p.OnStart(func(ctx context.Context, name string) {
+		switch name {
+		case stageFileVault:
+			r.runHook(hookPreFileVault, name, r.config.Scripts.PreFileVault)
+		case stageFirewall:
+			r.runHook(hookPreFirewall, name, r.config.Scripts.PreFirewall)
+		}
+	})
+	p.OnResult(func(result pipeline.Result) {
+		switch result.Status {
+		case pipeline.StatusFailed, pipeline.StatusTimedOut:
+			r.runHook(hookOnFailure, result.Name, r.config.Scripts.OnFailure)
+		case pipeline.StatusCompleted:
+			switch result.Name {
+			case stageAccounts:
+				r.runHook(hookPostAccounts, result.Name, r.config.Scripts.PostAccounts)
+			case stageFileVault:
+				r.runHook(hookPostFileVault, result.Name, r.config.Scripts.PostFileVault)
+			}
+		}
+	})
+
+	// catches cycles and unknown dependencies prior to the deployment starting.
+	_, err = p.Plan()
+	if err != nil {
+		return nil, err
+	}
+
+	return p, nil
+}
+
+// getInstallDirectoryFiles returns the files found in the install directories, it is flattened.
+func (r *RootData) getInstallDirectoryFiles() []string {
+	installDirectoryFiles := make([]string, 0)
+	for _, searchDir := range r.config.InstallDirectories {
+		searchFiles, err := utils.GetFiles(searchDir)
+		if err != nil {
+			r.log.Warn(fmt.Sprintf("Path %s does not exist, skipping path", searchDir))
+			continue
+		}
+
+		installDirectoryFiles = append(installDirectoryFiles, searchFiles...)
+	}
+
+	r.log.Debugf("File amount: %d | Directories: %v", len(installDirectoryFiles), r.config.InstallDirectories)
+
+	if len(installDirectoryFiles) < 1 {
+		srcPkgMsg := "No files found in search directories, packages will always be attempted to isntall"
+		r.log.Warn(srcPkgMsg)
+		fmt.Println(srcPkgMsg)
+	}
+
+	return installDirectoryFiles
+}
+
+// runDmgStage automatically mounts, extracts, and dismounts DMG files if they exist.
+func (r *RootData) runDmgStage() error {
+	r.log.Info("Searching for DMG files")
+	dmgFiles, err := r.dep.filehandler.ReadDir(r.metadata.Files.DistDirectory, ".dmg")
+	if err != nil {
+		r.log.Warnf("Failed to search directory: %v", err)
+		return nil
+	}
+
+	refused := len(r.dep.filehandler.GetChecksumErrors())
+
+	// this requires the use of --include to install properly.
+	volumeMounts := r.dep.filehandler.AttachDmgs(dmgFiles)
+	if len(volumeMounts) > 0 {
+		r.dep.filehandler.AddDmgPackages(volumeMounts, r.metadata.Files.DistDirectory)
+		r.dep.filehandler.DetachDmgs(volumeMounts)
+	}
+
+	return r.refusedFiles(refused)
+}
+
+// runAppsStage copies the app files into the Applications folder.
+func (r *RootData) runAppsStage() error {
+	appFiles, err := r.dep.filehandler.ReadDir(r.metadata.Files.DistDirectory, ".app")
+	if err != nil {
+		r.log.Warn(fmt.Sprintf("Failed to search directory: %v", err))
+	}
+	refused := len(r.dep.filehandler.GetChecksumErrors())
+
+	if len(appFiles) > 0 {
+		applicationDir := "/Applications"
+		r.dep.filehandler.CopyFiles(appFiles, applicationDir)
+	}
+
+	return r.refusedFiles(refused)
+}
+
+// refusedFiles returns an error with the files that were refused due to a failed checksum
+// verification, skipping the given number of previous checksum errors.
+func (r *RootData) refusedFiles(skip int) error {
+	checksumErrors := r.dep.filehandler.GetChecksumErrors()[skip:]
+	if len(checksumErrors) == 0 {
+		return nil
+	}
+
+	files := make([]string, 0, len(checksumErrors))
+	for _, err := range checksumErrors {
+		files = append(files, filepath.Base(err.Path))
+	}
+
+	return fmt.Errorf("refused files with failed checksums: %v", files)
+}
+
+// runFileVaultStage starts the FileVault process and sends the key to the server.
+func (r *RootData) runFileVaultStage() error {
+	request := r.newRequest()
+	filevaultPayload := requests.NewFileVaultPayload("")
+	fvKey := r.startFileVault(r.dep.filevault, request)
+
+	r.log.Debugf("FileVault payload flag: %v", r.context.SkipFileVaultPayload)
+	if r.context.SkipFileVaultPayload {
+		r.log.Info("Skipped FileVault payload request")
+		return nil
+	}
+
+	filevaultPayload.Key = fvKey
+	filevaultPayload.SetBody(r.metadata.SerialTag)
+
+	err := r.startRequest(filevaultPayload, request, r.config.ServerHost, "/api/fv")
+	if err != nil {
+		r.log.Warnf("Failed to send payload with FileVault key: %v", err)
+		r.warnFileVaultError(filevaultPayload)
+
+		return fmt.Errorf("failed to send FileVault key: %v", err)
+	}
+	r.journal.KeyEscrowed = true
+
+	return nil
+}
+
+// runLogStage sends the log file to the server.
+func (r *RootData) runLogStage() error {
+	currDate := time.Now().Format("2006-01-02")
+
+	err := r.sendLog(fmt.Sprintf("%s.%s.log", r.metadata.SerialTag, currDate))
+	if err != nil {
+		return err
+	}
+	r.journal.LogSent = true
+
+	return nil
+}
+
+// sendLog sends the log to the server, it is stored with the file name.
+func (r *RootData) sendLog(serverLogFile string) error {
+	r.log.Info("Sending log file to the server")
+
+	logPayload := requests.NewLogPayload(serverLogFile)
+
+	logPayload.Body = r.log.String()
+	err := r.startRequest(logPayload, r.newRequest(), r.config.ServerHost, "/api/log")
+	if err != nil {
+		r.log.Critical(fmt.Sprintf("Failed to send to data to server: %v", err))
+		return errors.New("failed to send log to server")
+	}
+
+	return nil
+}
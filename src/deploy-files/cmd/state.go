@@ -0,0 +1,139 @@
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+
+	"github.com/bobllor/macdeploy/src/deploy-files/pipeline"
+	"github.com/bobllor/macdeploy/src/deploy-files/state"
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	rootCmd.AddCommand(stateCmd)
+	stateCmd.AddCommand(stateShowCmd, stateResetCmd)
+}
+
+// stateFile is the file name of the deployment state journal, it is stored in the log directory.
+const stateFile string = "state.json"
+
+var stateLongDescription string = `
+Inspect or reset the state journal of the deployment. The journal is written after
+each stage of the deployment and is used by 'macdeploy --resume'.
+`
+
+var stateCmd = &cobra.Command{
+	Use:   "state <command>",
+	Long:  stateLongDescription,
+	Short: "Inspect the deployment state journal",
+}
+
+var stateShowCmd = &cobra.Command{
+	Use:   "show",
+	Short: "Displays the deployment state journal",
+	Run: func(cmd *cobra.Command, args []string) {
+		journal, err := state.Load(getStatePath())
+		if err != nil {
+			if errors.Is(err, os.ErrNotExist) {
+				fmt.Println("No deployment state found")
+				return
+			}
+
+			fmt.Printf("Failed to read deployment state: %v\n", err)
+			os.Exit(1)
+		}
+
+		out, err := json.MarshalIndent(journal, "", "  ")
+		if err != nil {
+			fmt.Printf("Failed to display deployment state: %v\n", err)
+			os.Exit(1)
+		}
+
+		fmt.Println(string(out))
+	},
+}
+
+var stateResetCmd = &cobra.Command{
+	Use:   "reset",
+	Short: "Removes the deployment state journal",
+	Run: func(cmd *cobra.Command, args []string) {
+		statePath := getStatePath()
+
+		if root.dep.runner.DryRun() {
+			root.dep.runner.Planf("remove %s", statePath)
+			return
+		}
+
+		err := state.Remove(statePath)
+		if err != nil {
+			fmt.Printf("Failed to remove deployment state: %v\n", err)
+			os.Exit(1)
+		}
+
+		fmt.Println("Deployment state has been reset")
+	},
+}
+
+// getStatePath returns the path of the state journal.
+func getStatePath() string {
+	return fmt.Sprintf("%s/%s/%s", os.Getenv("HOME"), defaultLogDir, stateFile)
+}
+
+// initJournal creates the state journal and records the stage results of the pipeline.
+// If resume is true, the previous journal is loaded and its completed stages are skipped.
+func (r *RootData) initJournal(resume bool) error {
+	statePath := getStatePath()
+	journal := state.NewJournal(statePath, r.metadata.SerialTag)
+
+	if resume {
+		prevJournal, err := state.Load(statePath)
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+
+		if prevJournal == nil {
+			fmt.Println("No deployment state found, starting a new deployment")
+		} else if prevJournal.SerialTag != r.metadata.SerialTag {
+			r.log.Warnf("State journal belongs to %s, starting a new deployment", prevJournal.SerialTag)
+			fmt.Println("Deployment state is from a different device, starting a new deployment")
+		} else {
+			journal = prevJournal
+
+			// stages removed from the config can exist in an older journal.
+			completed := slices.DeleteFunc(journal.CompletedStages(), func(name string) bool {
+				return !slices.Contains(r.pipeline.Stages(), name)
+			})
+
+			err = r.pipeline.Skip(completed...)
+			if err != nil {
+				return err
+			}
+
+			r.log.Infof("Resuming deployment, completed stages: %v", completed)
+			fmt.Printf("Resuming deployment, skipping completed stages: %v\n", completed)
+		}
+	}
+
+	r.journal = journal
+	r.pipeline.OnResult(func(result pipeline.Result) {
+		r.journal.SetStage(result)
+		r.saveJournal()
+	})
+
+	return nil
+}
+
+// saveJournal writes the state journal to the disk. Nothing is written during a dry run.
+func (r *RootData) saveJournal() {
+	if r.dep.runner.DryRun() {
+		return
+	}
+
+	err := r.journal.Save()
+	if err != nil {
+		r.log.Warnf("Failed to save deployment state to %s: %v", r.journal.Path(), err)
+	}
+}
@@ -0,0 +1,55 @@
+package cmd
+
+import (
+	"testing"
+
+	deploy "github.com/bobllor/macdeploy/src/deploy-files/cmd"
+	"github.com/bobllor/macdeploy/src/tests"
+)
+
+var failureBits []deploy.ExitCode = []deploy.ExitCode{
+	deploy.ExitPackages,
+	deploy.ExitAccounts,
+	deploy.ExitFileVault,
+	deploy.ExitServer,
+	deploy.ExitScripts,
+	deploy.ExitTimeout,
+}
+
+func TestExitCodesUnique(t *testing.T) {
+	outcomes := map[deploy.ExitCode]string{
+		deploy.ExitSuccess: "success",
+		deploy.ExitError:   "error",
+		deploy.ExitConfig:  "config",
+		deploy.ExitAborted: "aborted",
+	}
+
+	// every combination of the failure bits is a degraded deployment.
+	for mask := 1; mask < 1<<len(failureBits); mask++ {
+		code := deploy.ExitSuccess
+		for i, bit := range failureBits {
+			if mask&(1<<i) != 0 {
+				code |= bit
+			}
+		}
+
+		outcome, ok := outcomes[code]
+		tests.Checkf(t, ok, "degraded code %d is the same as %s", code, outcome)
+		outcomes[code] = "degraded"
+	}
+
+	for code, outcome := range outcomes {
+		tests.Checkf(t, code < 0 || code >= 128, "%s code %d is not in the range 0-127", outcome, code)
+	}
+}
+
+func TestExitCodesFailureBits(t *testing.T) {
+	seen := deploy.ExitSuccess
+
+	for _, bit := range failureBits {
+		tests.Checkf(t, bit&(bit-1) != 0, "failure code %d is not a single bit", bit)
+		tests.Checkf(t, bit&1 != 0, "failure code %d is odd", bit)
+		tests.Checkf(t, seen&bit != 0, "failure code %d is used twice", bit)
+		seen |= bit
+	}
+}
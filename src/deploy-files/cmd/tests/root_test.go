@@ -6,6 +6,8 @@ import (
 	"testing"
 
 	"github.com/bobllor/macdeploy/src/deploy-files/core"
+	"github.com/bobllor/macdeploy/src/deploy-files/runner"
+	"github.com/bobllor/macdeploy/src/deploy-files/yaml"
 	"github.com/bobllor/macdeploy/src/tests"
 )
 
@@ -19,7 +21,7 @@ var includeFiles []string = []string{
 }
 
 func TestAddPackageNoCSV(t *testing.T) {
-	handler := core.NewFileHandler(tests.TestLogger)
+	handler := core.NewFileHandler(tests.TestLogger, runner.NewFake())
 
 	handler.AddPackages(includeFiles)
 
@@ -40,7 +42,7 @@ func TestAddPackageCSV(t *testing.T) {
 		newIncludeFiles[i] = s + "," + installFiles[i]
 	}
 
-	handler := core.NewFileHandler(tests.TestLogger)
+	handler := core.NewFileHandler(tests.TestLogger, runner.NewFake())
 
 	handler.AddPackages(newIncludeFiles)
 
@@ -53,7 +55,7 @@ func TestAddPackageCSV(t *testing.T) {
 		dVal, ok := packages[includeFile]
 		tests.Checkf(t, ok == false, "package %s not found in %v", includeFile, packages)
 
-		dInstallFile := strings.Join(dVal, ",")
+		dInstallFile := strings.Join(dVal.Installed, ",")
 
 		tests.Checkf(t, installFile != dInstallFile, "install file %s does not match baseline %s", dInstallFile, installFile)
 	}
@@ -61,13 +63,13 @@ func TestAddPackageCSV(t *testing.T) {
 
 func TestAddMapPackages(t *testing.T) {
 	// mimics the packages added via config
-	configPkgs := make(map[string][]string)
+	configPkgs := make(map[string]yaml.Package)
 
 	for _, file := range includeFiles {
-		configPkgs[file] = []string{}
+		configPkgs[file] = yaml.Package{Installed: []string{}}
 	}
 
-	handler := core.NewFileHandler(tests.TestLogger)
+	handler := core.NewFileHandler(tests.TestLogger, runner.NewFake())
 
 	handler.AddMapPackages(configPkgs)
 
@@ -77,14 +79,14 @@ func TestAddMapPackages(t *testing.T) {
 		key = strings.ToLower(key)
 		pkgVal, ok := packagesMap[key]
 
-		tests.Checkf(t, ok == false, "key %s not found in packages %v", val, packagesMap)
-		tests.Checkf(t, len(val) != len(pkgVal), "expected %s to be len 0, got %d", key, len(val))
+		tests.Checkf(t, ok == false, "key %s not found in packages %v", key, packagesMap)
+		tests.Checkf(t, len(val.Installed) != len(pkgVal.Installed), "expected %s to be len 0, got %d", key, len(val.Installed))
 	}
 }
 
 func TestAddMapPackagesWithInstallFiles(t *testing.T) {
 	// mimics the packages added via config
-	configPkgs := make(map[string][]string)
+	configPkgs := make(map[string]yaml.Package)
 
 	installFiles := [][]string{
 		{"chrome.app", "chrome enterprise.app"},
@@ -92,10 +94,10 @@ func TestAddMapPackagesWithInstallFiles(t *testing.T) {
 	}
 
 	for i, file := range includeFiles {
-		configPkgs[file] = installFiles[i]
+		configPkgs[file] = yaml.Package{Installed: installFiles[i]}
 	}
 
-	handler := core.NewFileHandler(tests.TestLogger)
+	handler := core.NewFileHandler(tests.TestLogger, runner.NewFake())
 
 	handler.AddMapPackages(configPkgs)
 
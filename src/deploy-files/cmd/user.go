@@ -9,6 +9,7 @@ import (
 	embedhandler "github.com/bobllor/macdeploy/src/config"
 	"github.com/bobllor/macdeploy/src/deploy-files/core"
 	"github.com/bobllor/macdeploy/src/deploy-files/logger"
+	"github.com/bobllor/macdeploy/src/deploy-files/prompt"
 	"github.com/bobllor/macdeploy/src/deploy-files/scripts"
 	"github.com/bobllor/macdeploy/src/deploy-files/utils"
 	"github.com/bobllor/macdeploy/src/deploy-files/yaml"
@@ -62,7 +63,7 @@ var userCreateCmd = &cobra.Command{
 	Run: func(cmd *cobra.Command, args []string) {
 		adminInfo, err := newAdminInfo()
 		if err != nil {
-			fmt.Println("Failed to retrieve admin information")
+			fmt.Printf("Failed to retrieve admin information: %v\n", err)
 			os.Exit(1)
 		}
 		logLevel := getLogLevel(userCobra.logvars)
@@ -71,6 +72,7 @@ var userCreateCmd = &cobra.Command{
 		if file != nil {
 			defer file.Close()
 		}
+		log.AddSecrets(userCobra.UserInfo.Password)
 
 		// used for applying password policies
 		// manual password policies are not supported at the time (5/8/2026)
@@ -143,7 +145,7 @@ var userDeleteCmd = &cobra.Command{
 	Run: func(cmd *cobra.Command, args []string) {
 		adminInfo, err := newAdminInfo()
 		if err != nil {
-			fmt.Println("Failed to retrieve admin information")
+			fmt.Printf("Failed to retrieve admin information: %v\n", err)
 			os.Exit(1)
 		}
 
@@ -178,7 +180,7 @@ var userAdminGrantCmd = &cobra.Command{
 	Run: func(cmd *cobra.Command, args []string) {
 		adminInfo, err := newAdminInfo()
 		if err != nil {
-			fmt.Println("Failed to retrieve admin information")
+			fmt.Printf("Failed to retrieve admin information: %v\n", err)
 			os.Exit(1)
 		}
 		logLevel := getLogLevel(userCobra.logvars)
@@ -215,7 +217,7 @@ var userAdminRevokeCmd = &cobra.Command{
 	Run: func(cmd *cobra.Command, args []string) {
 		adminInfo, err := newAdminInfo()
 		if err != nil {
-			fmt.Println("Failed to retrieve admin information")
+			fmt.Printf("Failed to retrieve admin information: %v\n", err)
 			os.Exit(1)
 		}
 		logLevel := getLogLevel(userCobra.logvars)
@@ -252,7 +254,7 @@ var userListCmd = &cobra.Command{
 			defer file.Close()
 		}
 
-		um := core.NewUser(userCobra.UserInfo, scripts.NewScript(), log)
+		um := core.NewUser(userCobra.UserInfo, scripts.NewScript(), log, root.dep.runner)
 
 		users, err := um.List()
 		if err != nil {
@@ -275,10 +277,12 @@ func newUserCmdStructs(adminInfo *yaml.UserInfo, logLevel int) (*core.UserMaker,
 	if err != nil {
 		log = logger.NewStdoutLogger(logger.Lsilent)
 	}
+	log.AddSecrets(adminInfo.Password)
+	root.dep.runner.SetRedactor(log)
 
 	bashScripts := scripts.NewScript()
-	um := core.NewUser(*adminInfo, bashScripts, log)
-	fv := core.NewFileVault(*adminInfo, bashScripts, log)
+	um := core.NewUser(*adminInfo, bashScripts, log, root.dep.runner)
+	fv := core.NewFileVault(*adminInfo, bashScripts, log, root.dep.runner)
 
 	return um, fv, log, file
 }
@@ -290,7 +294,9 @@ func newUserCmdStructs(adminInfo *yaml.UserInfo, logLevel int) (*core.UserMaker,
 // Other errors can occur during username and password setup.
 func newAdminInfo() (*yaml.UserInfo, error) {
 	adminInfo := &yaml.UserInfo{}
-	err := adminInfo.SetUsername()
+	adminInfo.SetFromEnv(envAdminUsername, envAdminPassword)
+
+	err := adminInfo.SetUsername(root.dep.runner)
 	if err != nil {
 		err := adminInfo.SetUsernameManual()
 		if err != nil {
@@ -298,12 +304,19 @@ func newAdminInfo() (*yaml.UserInfo, error) {
 		}
 	}
 
-	fmt.Println("Admin password required")
-	err = adminInfo.SetPassword(false)
-	if err != nil {
-		return nil, errors.New("failed to set admin password")
+	if adminInfo.Password == "" {
+		err = prompt.Check("admin password", envAdminPassword)
+		if err != nil {
+			return nil, err
+		}
+
+		fmt.Println("Admin password required")
+		err = adminInfo.SetPassword(root.dep.runner, false)
+		if err != nil {
+			return nil, errors.New("failed to set admin password")
+		}
 	}
-	err = adminInfo.InitializeSudo()
+	err = adminInfo.InitializeSudo(root.dep.runner)
 	if err != nil {
 		return nil, errors.New("failed to initialize sudo")
 	}
@@ -0,0 +1,60 @@
+package cmd
+
+import (
+	"fmt"
+	"time"
+)
+
+// startWatchdog logs the running stage and commands at every interval, so a stuck
+// stage is visible in the log and the terminal. A zero interval disables the watchdog.
+//
+// It returns a function that stops the watchdog.
+func (r *RootData) startWatchdog(interval time.Duration) func() {
+	if interval <= 0 || r.dep.runner.DryRun() {
+		return func() {}
+	}
+
+	done := make(chan struct{})
+	ticker := time.NewTicker(interval)
+
+	go func() {
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				r.watch()
+			}
+		}
+	}()
+
+	return func() {
+		close(done)
+	}
+}
+
+// watch logs the running stage and the commands that are running.
+func (r *RootData) watch() {
+	stage, elapsed := r.pipeline.Current()
+	if stage == "" {
+		return
+	}
+
+	elapsed = elapsed.Round(time.Second)
+	commands := r.dep.runner.Running()
+
+	if len(commands) == 0 {
+		r.log.Infof("Watchdog: stage %s is running for %s", stage, elapsed)
+		return
+	}
+
+	for _, command := range commands {
+		running := time.Since(command.Started).Round(time.Second)
+
+		r.log.Warnf("Watchdog: stage %s is running for %s, waiting on %s for %s",
+			stage, elapsed, command.Command, running)
+		fmt.Printf("Still waiting on %s (%s)\n", command.Command, running)
+	}
+}
@@ -0,0 +1,178 @@
+package core
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/bobllor/macdeploy/src/deploy-files/runner"
+	"github.com/bobllor/macdeploy/src/deploy-files/utils"
+	"github.com/bobllor/macdeploy/src/deploy-files/yaml"
+)
+
+// defaultAppDirectory is the directory searched for apps if no app directories are set.
+const defaultAppDirectory string = "/Applications"
+
+// AppInfo is the bundle information of an installed app, read from its Info.plist.
+type AppInfo struct {
+	Path     string
+	BundleID string
+	Version  string
+}
+
+// SetAppDirectories sets the directories that are searched for the apps of the packages
+// with a bundle identifier. If empty, the apps are searched in /Applications.
+func (f *FileHandler) SetAppDirectories(directories []string) {
+	f.appDirectories = directories
+}
+
+// ReadAppInfo reads the bundle identifier (CFBundleIdentifier) and the version
+// (CFBundleShortVersionString) from the Info.plist of the app. CFBundleVersion is used
+// if the app has no short version. A binary plist is converted with plutil.
+//
+// An error is returned if the Info.plist fails to be read or has no bundle identifier.
+func (f *FileHandler) ReadAppInfo(appPath string) (AppInfo, error) {
+	plistPath := filepath.Join(appPath, "Contents", "Info.plist")
+
+	data, err := os.ReadFile(plistPath)
+	if err != nil {
+		return AppInfo{}, err
+	}
+
+	if bytes.HasPrefix(data, []byte("bplist")) {
+		data, err = runner.Query(f.runner, "plutil", "-convert", "xml1", "-o", "-", plistPath)
+		if err != nil {
+			return AppInfo{}, fmt.Errorf("failed to convert %s: %v", plistPath, err)
+		}
+	}
+
+	values, err := plistStrings(data)
+	if err != nil {
+		return AppInfo{}, fmt.Errorf("failed to parse %s: %v", plistPath, err)
+	}
+
+	info := AppInfo{
+		Path:     appPath,
+		BundleID: values["CFBundleIdentifier"],
+		Version:  values["CFBundleShortVersionString"],
+	}
+	if info.Version == "" {
+		info.Version = values["CFBundleVersion"]
+	}
+	if info.BundleID == "" {
+		return AppInfo{}, fmt.Errorf("no bundle identifier found in %s", plistPath)
+	}
+
+	return info, nil
+}
+
+// FindApp searches the app directories for the app with the bundle identifier. Only the
+// apps at the top level of the directories are searched, the bundle identifier is not
+// case sensitive.
+//
+// False is returned if no app is found.
+func (f *FileHandler) FindApp(bundleID string) (AppInfo, bool) {
+	directories := f.appDirectories
+	if len(directories) == 0 {
+		directories = []string{defaultAppDirectory}
+	}
+
+	for _, directory := range directories {
+		entries, err := os.ReadDir(directory)
+		if err != nil {
+			f.log.Debugf("Failed to read app directory %s: %v", directory, err)
+			continue
+		}
+
+		for _, entry := range entries {
+			if !strings.HasSuffix(strings.ToLower(entry.Name()), ".app") {
+				continue
+			}
+
+			info, err := f.ReadAppInfo(filepath.Join(directory, entry.Name()))
+			if err != nil {
+				f.log.Debugf("Skipped app %s: %v", entry.Name(), err)
+				continue
+			}
+
+			if strings.EqualFold(info.BundleID, bundleID) {
+				return info, true
+			}
+		}
+	}
+
+	return AppInfo{}, false
+}
+
+// hasApp returns true if the app of the package is found and is not older than the
+// minimum version of the package.
+func (f *FileHandler) hasApp(name string, pkg yaml.Package) bool {
+	app, ok := f.FindApp(pkg.BundleID)
+	if !ok {
+		f.log.Infof("No app found with bundle identifier %s for package %s", pkg.BundleID, name)
+		return false
+	}
+
+	f.log.Debugf("Package: %s | App: %s | Version: %s", name, app.Path, app.Version)
+
+	if pkg.MinVersion != "" && utils.CompareVersions(app.Version, pkg.MinVersion) < 0 {
+		f.log.Infof("Found %s version %s, older than the minimum version %s of package %s",
+			filepath.Base(app.Path), app.Version, pkg.MinVersion, name)
+		fmt.Printf("%s is outdated (%s < %s), upgrading\n", name, app.Version, pkg.MinVersion)
+
+		return false
+	}
+
+	return true
+}
+
+// plistStrings returns the string values of the top level dictionary of an XML plist
+// by the key. Values of other types are skipped.
+func plistStrings(data []byte) (map[string]string, error) {
+	decoder := xml.NewDecoder(bytes.NewReader(data))
+	values := map[string]string{}
+
+	// the entries of the top level dictionary are at depth 2: <plist><dict>.
+	depth := 0
+	key := ""
+	for {
+		token, err := decoder.Token()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		switch t := token.(type) {
+		case xml.StartElement:
+			if depth != 2 {
+				depth++
+				continue
+			}
+
+			switch t.Name.Local {
+			case "key":
+				err = decoder.DecodeElement(&key, &t)
+			case "string":
+				var value string
+				err = decoder.DecodeElement(&value, &t)
+				values[key] = strings.TrimSpace(value)
+			default:
+				err = decoder.Skip()
+			}
+			if err != nil {
+				return nil, err
+			}
+		case xml.EndElement:
+			depth--
+		}
+	}
+
+	return values, nil
+}
@@ -0,0 +1,117 @@
+package core
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/bobllor/assert"
+	"github.com/bobllor/macdeploy/src/deploy-files/runner"
+	"github.com/bobllor/macdeploy/src/deploy-files/yaml"
+	"github.com/bobllor/macdeploy/src/tests"
+)
+
+// infoPlist returns an XML Info.plist with the bundle identifier and the version.
+func infoPlist(bundleID string, version string) string {
+	return fmt.Sprintf(`<?xml version="1.0" encoding="UTF-8"?>
+<!DOCTYPE plist PUBLIC "-//Apple//DTD PLIST 1.0//EN" "http://www.apple.com/DTDs/PropertyList-1.0.dtd">
+<plist version="1.0">
+<dict>
+	<key>CFBundleDocumentTypes</key>
+	<array>
+		<dict>
+			<key>CFBundleIdentifier</key>
+			<string>com.example.nested</string>
+		</dict>
+	</array>
+	<key>CFBundleIdentifier</key>
+	<string>%s</string>
+	<key>LSRequiresNativeExecution</key>
+	<true/>
+	<key>CFBundleShortVersionString</key>
+	<string>%s</string>
+</dict>
+</plist>
+`, bundleID, version)
+}
+
+// writeApp creates an app bundle in the directory with the Info.plist content.
+func writeApp(t *testing.T, dir string, name string, plist string) string {
+	t.Helper()
+
+	appPath := filepath.Join(dir, name)
+	err := os.MkdirAll(filepath.Join(appPath, "Contents"), 0o755)
+	if err != nil {
+		t.Fatalf("failed to create app: %v", err)
+	}
+
+	err = os.WriteFile(filepath.Join(appPath, "Contents", "Info.plist"), []byte(plist), 0o644)
+	if err != nil {
+		t.Fatalf("failed to write Info.plist: %v", err)
+	}
+
+	return appPath
+}
+
+func TestReadAppInfo(t *testing.T) {
+	handler := NewFileHandler(tests.TestLogger, runner.NewFake())
+
+	appPath := writeApp(t, t.TempDir(), "Google Chrome.app", infoPlist("com.google.Chrome", "120.0.6099.109"))
+
+	info, err := handler.ReadAppInfo(appPath)
+	assert.Nil(t, err)
+	assert.Equal(t, info.BundleID, "com.google.Chrome")
+	assert.Equal(t, info.Version, "120.0.6099.109")
+
+	t.Run("Binary Plist", func(t *testing.T) {
+		fake := runner.NewFake()
+		fake.On("plutil -convert xml1", infoPlist("com.example.Binary", "2.1"))
+		handler := NewFileHandler(tests.TestLogger, fake)
+
+		appPath := writeApp(t, t.TempDir(), "Binary.app", "bplist00\x00\x01")
+
+		info, err := handler.ReadAppInfo(appPath)
+		assert.Nil(t, err)
+		assert.Equal(t, info.BundleID, "com.example.Binary")
+		assert.Equal(t, info.Version, "2.1")
+	})
+
+	t.Run("Missing Bundle ID", func(t *testing.T) {
+		appPath := writeApp(t, t.TempDir(), "Empty.app", "<plist><dict></dict></plist>")
+
+		_, err := handler.ReadAppInfo(appPath)
+		assert.NotNil(t, err)
+	})
+}
+
+func TestInstallPackagesMinVersion(t *testing.T) {
+	appDir := t.TempDir()
+	writeApp(t, appDir, "Google Chrome.app", infoPlist("com.google.Chrome", "119.0.6045.199"))
+	writeApp(t, appDir, "Slack.app", infoPlist("com.tinyspeck.slackmacgap", "4.36.140"))
+	writeApp(t, appDir, "Zoom.app", infoPlist("us.zoom.xos", "5.17.5 (29101)"))
+
+	fake := runner.NewFake()
+	handler := NewFileHandler(tests.TestLogger, fake)
+	handler.SetAppDirectories([]string{filepath.Join(appDir, "missing"), appDir})
+	handler.AddMapPackages(map[string]yaml.Package{
+		"chrome.pkg":  {BundleID: "com.google.Chrome", MinVersion: "120.0"},
+		"slack.pkg":   {BundleID: "COM.TINYSPECK.SLACKMACGAP", MinVersion: "4.36"},
+		"zoom.pkg":    {BundleID: "us.zoom.xos", MinVersion: "5.17.5"},
+		"firefox.pkg": {BundleID: "org.mozilla.firefox"},
+	})
+
+	packages := []string{"/tmp/dist/chrome.pkg", "/tmp/dist/slack.pkg", "/tmp/dist/zoom.pkg", "/tmp/dist/firefox.pkg"}
+	installed := handler.InstallPackages(packages, []string{})
+
+	assert.Equal(t, installed, 4)
+	assert.Equal(t, fake.Ran("chrome.pkg"), true)
+	assert.Equal(t, fake.Ran("firefox.pkg"), true)
+	assert.Equal(t, fake.Ran("slack.pkg"), false)
+	assert.Equal(t, fake.Ran("zoom.pkg"), false)
+
+	skipped := handler.GetSkippedPackages()
+	assert.Equal(t, len(skipped), 2)
+	assert.Equal(t, strings.Contains(strings.Join(skipped, ","), "slack.pkg"), true)
+}
@@ -0,0 +1,75 @@
+package core
+
+import (
+	"fmt"
+	"maps"
+	"path"
+	"strings"
+
+	"github.com/bobllor/macdeploy/src/deploy-files/utils"
+	"github.com/bobllor/macdeploy/src/deploy-files/yaml"
+)
+
+// SetDevice sets the device facts that the package conditions are evaluated against.
+func (f *FileHandler) SetDevice(device utils.Device) {
+	f.device = device
+}
+
+// GetConditionSkippedPackages returns the packages that were skipped by InstallPackages
+// because the device does not meet their conditions, k:v <package>:<reason>.
+func (f *FileHandler) GetConditionSkippedPackages() map[string]string {
+	return maps.Clone(f.conditionSkipped)
+}
+
+// unmetCondition returns the reason the device does not meet the conditions of the package.
+// An empty string is returned if every condition is met.
+//
+// A condition is not met if its device fact is unknown.
+func (f *FileHandler) unmetCondition(pkg yaml.Package) string {
+	device := f.device
+
+	if pkg.Arch != "" && !strings.EqualFold(pkg.Arch, device.Arch) {
+		return fmt.Sprintf("requires arch %s, device arch is %s", pkg.Arch, orUnknown(device.Arch))
+	}
+
+	if pkg.MinOS != "" && utils.CompareVersions(device.OSVersion, pkg.MinOS) < 0 {
+		return fmt.Sprintf("requires macOS %s or later, device has macOS %s", pkg.MinOS, orUnknown(device.OSVersion))
+	}
+
+	if pkg.MaxOS != "" && !utils.WithinMaxVersion(device.OSVersion, pkg.MaxOS) {
+		return fmt.Sprintf("requires macOS %s or earlier, device has macOS %s", pkg.MaxOS, orUnknown(device.OSVersion))
+	}
+
+	if len(pkg.Models) > 0 && !matchModel(pkg.Models, device.Model) {
+		return fmt.Sprintf("requires model %s, device model is %s",
+			strings.Join(pkg.Models, " or "), orUnknown(device.Model))
+	}
+
+	return ""
+}
+
+// matchModel returns true if the model matches any of the patterns. The patterns
+// use the syntax of path.Match and are not case sensitive.
+func matchModel(patterns []string, model string) bool {
+	if model == "" {
+		return false
+	}
+
+	for _, pattern := range patterns {
+		ok, err := path.Match(strings.ToLower(strings.TrimSpace(pattern)), strings.ToLower(model))
+		if err == nil && ok {
+			return true
+		}
+	}
+
+	return false
+}
+
+// orUnknown returns the value, or "unknown" if it is empty.
+func orUnknown(value string) string {
+	if value == "" {
+		return "unknown"
+	}
+
+	return value
+}
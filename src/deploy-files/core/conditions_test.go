@@ -0,0 +1,104 @@
+package core
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/bobllor/assert"
+	"github.com/bobllor/macdeploy/src/deploy-files/runner"
+	"github.com/bobllor/macdeploy/src/deploy-files/utils"
+	"github.com/bobllor/macdeploy/src/deploy-files/yaml"
+	"github.com/bobllor/macdeploy/src/tests"
+)
+
+func TestUnmetCondition(t *testing.T) {
+	handler := NewFileHandler(tests.TestLogger, runner.NewFake())
+	handler.SetDevice(utils.Device{Arch: "arm64", OSVersion: "14.5", Model: "MacBookPro18,3"})
+
+	met := []yaml.Package{
+		{},
+		{Arch: "arm64"},
+		{MinOS: "14.0", MaxOS: "14"},
+		{MaxOS: "14.5"},
+		{Models: []string{"Mac14,*", "macbookpro*"}},
+	}
+	for _, pkg := range met {
+		reason := handler.unmetCondition(pkg)
+		tests.Checkf(t, reason != "", "expected conditions %+v to be met, got %s", pkg, reason)
+	}
+
+	unmet := map[string]yaml.Package{
+		"requires arch amd64, device arch is arm64":                  {Arch: "amd64"},
+		"requires macOS 15 or later, device has macOS 14.5":          {MinOS: "15"},
+		"requires macOS 14.4 or earlier, device has macOS 14.5":      {MaxOS: "14.4"},
+		"requires model MacBookAir*, device model is MacBookPro18,3": {Models: []string{"MacBookAir*"}},
+	}
+	for expected, pkg := range unmet {
+		assert.Equal(t, handler.unmetCondition(pkg), expected)
+	}
+
+	t.Run("Unknown Device", func(t *testing.T) {
+		handler := NewFileHandler(tests.TestLogger, runner.NewFake())
+
+		assert.Equal(t, handler.unmetCondition(yaml.Package{MinOS: "14"}),
+			"requires macOS 14 or later, device has macOS unknown")
+		assert.Equal(t, handler.unmetCondition(yaml.Package{}), "")
+	})
+}
+
+func TestInstallPackagesConditions(t *testing.T) {
+	fake := runner.NewFake()
+
+	handler := NewFileHandler(tests.TestLogger, fake)
+	handler.SetDevice(utils.Device{Arch: "arm64", OSVersion: "14.5", Model: "Mac14,2"})
+	handler.AddMapPackages(map[string]yaml.Package{
+		"intel-agent.pkg":  {Arch: "amd64"},
+		"apple-agent.pkg":  {Arch: "arm64", MinOS: "14"},
+		"apple-config.pkg": {DependsOn: []string{"apple-agent.pkg"}},
+	})
+
+	// intel-agent.pkg is not in the dist directory, it must not be reported as missing.
+	packages := []string{"/tmp/dist/apple-agent.pkg", "/tmp/dist/apple-config.pkg"}
+	installed := handler.InstallPackages(packages, []string{})
+
+	assert.Equal(t, installed, 2)
+	assert.Equal(t, fake.Ran("installer -pkg /tmp/dist/apple-agent.pkg"), true)
+	assert.Equal(t, fake.Ran("installer -pkg /tmp/dist/apple-config.pkg"), true)
+	assert.Equal(t, len(handler.GetFailedPackages()), 0)
+
+	skipped := handler.GetConditionSkippedPackages()
+	assert.Equal(t, len(skipped), 1)
+	assert.Equal(t, skipped["intel-agent.pkg"], "requires arch amd64, device arch is arm64")
+}
+
+func TestInstallPackagesConditionsDependents(t *testing.T) {
+	fake := runner.NewFake()
+
+	handler := NewFileHandler(tests.TestLogger, fake)
+	handler.SetDevice(utils.Device{Arch: "arm64", OSVersion: "14.5", Model: "Mac14,2"})
+	handler.AddMapPackages(map[string]yaml.Package{
+		"intel-agent.pkg":   {Arch: "amd64"},
+		"agent-config.pkg":  {DependsOn: []string{"Intel-Agent.pkg"}},
+		"agent-plugin.pkg":  {DependsOn: []string{"agent-config.pkg"}},
+		"standalone.pkg":    {},
+		"missing-agent.pkg": {},
+		"missing-child.pkg": {DependsOn: []string{"missing-agent.pkg"}},
+	})
+
+	packages := []string{"/tmp/dist/agent-config.pkg", "/tmp/dist/agent-plugin.pkg", "/tmp/dist/standalone.pkg"}
+	installed := handler.InstallPackages(packages, []string{})
+
+	assert.Equal(t, installed, 1)
+	assert.Equal(t, fake.Ran("installer -pkg /tmp/dist/agent-config.pkg"), false)
+	assert.Equal(t, fake.Ran("installer -pkg /tmp/dist/agent-plugin.pkg"), false)
+
+	skipped := handler.GetConditionSkippedPackages()
+	assert.Equal(t, len(skipped), 3)
+	assert.Equal(t, skipped["agent-config.pkg"], "depends on Intel-Agent.pkg, which is not applicable")
+	assert.Equal(t, skipped["agent-plugin.pkg"], "depends on agent-config.pkg, which is not applicable")
+
+	// a dependency that fails is still a failure of its dependents.
+	failed := handler.GetFailedPackages()
+	assert.Equal(t, slices.Contains(failed, "missing-child.pkg"), true)
+	assert.Equal(t, slices.Contains(failed, "agent-config.pkg"), false)
+}
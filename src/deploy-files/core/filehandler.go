@@ -5,27 +5,52 @@ import (
 	"fmt"
 	"io"
 	"io/fs"
+	"maps"
 	"os"
-	"os/exec"
 	"path/filepath"
 	"runtime"
+	"slices"
 	"strings"
+	"time"
 
+	"github.com/bobllor/macdeploy/src/deploy-files/checksum"
 	"github.com/bobllor/macdeploy/src/deploy-files/logger"
+	"github.com/bobllor/macdeploy/src/deploy-files/runner"
+	"github.com/bobllor/macdeploy/src/deploy-files/utils"
+	"github.com/bobllor/macdeploy/src/deploy-files/yaml"
 )
 
 type FileHandler struct {
-	packagesToInstall map[string][]string
+	packagesToInstall map[string]yaml.Package
+	installedPackages []string          // Packages installed by InstallPackages.
+	skippedPackages   []string          // Packages with an existing installation.
+	conditionSkipped  map[string]string // Packages with conditions not met by the device, k:v <package>:<reason>.
+	failedPackages    []string          // Packages that failed to install or were not found.
+	mountedVolumes    []string          // Volumes attached by AttachDmgs that are not detached.
 	log               *logger.Logger
-	scriptsPathCache  map[string]string // Cache for script paths, k:v <file name>:<file path>. The key is lowercase.
+	scriptsPathCache  map[string]string       // Cache for script paths, k:v <file name>:<file path>. The key is lowercase.
+	checksums         map[string]string       // Checksums of the dist files, k:v <file name>:<checksum>. The key is lowercase.
+	checksumErrors    []*checksum.VerifyError // Files refused by a failed checksum verification.
+	appDirectories    []string                // Directories searched for the apps of the packages with a bundle identifier.
+	device            utils.Device            // Facts of the device, used to evaluate the package conditions.
+	runner            runner.Runner
 }
 
 // NewFileHandler creates a new FileHandler to handle package installations.
-func NewFileHandler(logger *logger.Logger) *FileHandler {
+func NewFileHandler(logger *logger.Logger, runner runner.Runner) *FileHandler {
 	handler := FileHandler{
-		packagesToInstall: make(map[string][]string),
+		packagesToInstall: make(map[string]yaml.Package),
+		installedPackages: make([]string, 0),
+		skippedPackages:   make([]string, 0),
+		conditionSkipped:  make(map[string]string),
+		failedPackages:    make([]string, 0),
+		mountedVolumes:    make([]string, 0),
 		log:               logger,
 		scriptsPathCache:  make(map[string]string),
+		checksums:         make(map[string]string),
+		checksumErrors:    make([]*checksum.VerifyError, 0),
+		appDirectories:    make([]string, 0),
+		runner:            runner,
 	}
 
 	return &handler
@@ -41,14 +66,11 @@ func (f *FileHandler) InstallRosetta() error {
 		return nil
 	}
 
-	cmd := "pkgutil --pkgs | grep -i rosetta"
-
-	// due to using grep, if rosetta is not installed it is an error- so errors MUST be ignored.
-	// instead the output will be used to handle the error.
-	out, _ := exec.Command("bash", "-c", cmd).Output()
-	if string(out) == "" {
-		_, installErr := exec.Command("sudo", "softwareupdate", "--install-rosetta",
-			"--agree-to-license").Output()
+	// errors are ignored, the output will be used to handle the error.
+	out, _ := runner.Output(f.runner, "pkgutil", "--pkgs")
+	if !strings.Contains(strings.ToLower(string(out)), "rosetta") {
+		_, installErr := runner.Output(f.runner, "sudo", "softwareupdate", "--install-rosetta",
+			"--agree-to-license")
 		if installErr != nil {
 			return errors.New("rosetta failed to install")
 		}
@@ -88,7 +110,7 @@ func (f *FileHandler) AddPackages(packagesToAdd []string) {
 			}
 		}
 
-		f.packagesToInstall[pkg] = pkgInstalledArr
+		f.packagesToInstall[pkg] = yaml.Package{Installed: pkgInstalledArr}
 		f.log.Info(fmt.Sprintf("Added '%s' to the installation list", pkg))
 	}
 }
@@ -96,10 +118,10 @@ func (f *FileHandler) AddPackages(packagesToAdd []string) {
 // AddMapPackages adds new packages to the file handler using a map. All package names
 // will be lowered.
 //
-// packagesToAdd is a map of a string with a slice of strings.
-// The key represents the package to install, while its slice values are
-// the installation files of the package.
-func (f *FileHandler) AddMapPackages(packagesToAdd map[string][]string) {
+// packagesToAdd is a map of a string with a Package.
+// The key represents the package to install, while its value is
+// the installation files and the options of the package.
+func (f *FileHandler) AddMapPackages(packagesToAdd map[string]yaml.Package) {
 	for key, val := range packagesToAdd {
 		key = strings.ToLower(key)
 
@@ -171,6 +193,12 @@ func (f *FileHandler) ReadDir(directoryPath string, searchPattern string) ([]str
 // It will return a number of packages that were successfully installed. If a package failed to install,
 // then this will be skipped and logged.
 //
+// The packages are installed in the order of yaml.SortPackages. A package is not installed if one
+// of its dependencies failed to install, and it is added to the failed packages.
+//
+// The conditions of every package are checked against the device before any install. A package
+// with an unmet condition is skipped, it does not fail its dependents.
+//
 // packagesPath is a slice of paths of the .pkg file.
 //
 // installDirectoryFiles is a slice of file paths that represent the installed .pkg file. The elements are
@@ -184,15 +212,71 @@ func (f *FileHandler) InstallPackages(packagesPath []string, installDirectoryFil
 		return installedFiles
 	}
 
-	for pkg, installedNames := range f.packagesToInstall {
-		isInstalled := f.IsInstalled(installedNames, installDirectoryFiles)
+	order, err := yaml.SortPackages(f.packagesToInstall)
+	if err != nil {
+		f.log.Warnf("Failed to sort packages, installing by name: %v", err)
+		order = slices.Sorted(maps.Keys(f.packagesToInstall))
+	}
+	f.log.Debugf("Package install order: %v", order)
+
+	f.log.Debugf("Device: %s", f.device)
+	for _, pkg := range order {
+		reason := f.unmetCondition(f.packagesToInstall[pkg])
+		if reason != "" {
+			f.log.Infof("Skipped package %s by its conditions, it %s", pkg, reason)
+			fmt.Printf("Skipped %s, it %s\n", pkg, reason)
+
+			f.conditionSkipped[pkg] = reason
+		}
+	}
+
+	// packages that were not installed, used to skip their dependents.
+	notInstalled := make(map[string]bool)
+
+	for _, pkg := range order {
+		entry := f.packagesToInstall[pkg]
+
+		if _, ok := f.conditionSkipped[pkg]; ok {
+			notInstalled[pkg] = true
+			continue
+		}
+
+		failedDep := ""
+		for _, dep := range entry.DependsOn {
+			if notInstalled[strings.ToLower(strings.TrimSpace(dep))] {
+				failedDep = dep
+				break
+			}
+		}
+
+		// a package is not applicable to the device if one of its dependencies is not.
+		if _, ok := f.conditionSkipped[strings.ToLower(strings.TrimSpace(failedDep))]; ok {
+			reason := fmt.Sprintf("depends on %s, which is not applicable", failedDep)
+			f.log.Infof("Skipped package %s by its conditions, it %s", pkg, reason)
+			fmt.Printf("Skipped %s, it %s\n", pkg, reason)
+
+			notInstalled[pkg] = true
+			f.conditionSkipped[pkg] = reason
+			continue
+		}
+		if failedDep != "" {
+			f.log.Warnf("Skipped package %s, its dependency %s failed to install", pkg, failedDep)
+			fmt.Printf("Skipped %s, its dependency %s failed to install\n", pkg, failedDep)
+
+			notInstalled[pkg] = true
+			f.failedPackages = append(f.failedPackages, pkg)
+			continue
+		}
+
+		isInstalled := f.isPackageInstalled(pkg, entry, installDirectoryFiles)
 
 		if isInstalled {
 			f.log.Info(fmt.Sprintf("Found existing installation for package %s", pkg))
-			f.log.Debug(fmt.Sprintf("Package: %s | Given package name: %s", pkg, installedNames))
+			f.log.Debug(fmt.Sprintf("Package: %s | Given package name: %s", pkg, entry.Installed))
 			fmt.Printf("%s is already installed\n", pkg)
 
 			installedFiles += 1
+			f.skippedPackages = append(f.skippedPackages, pkg)
 			continue
 		}
 
@@ -209,17 +293,28 @@ func (f *FileHandler) InstallPackages(packagesPath []string, installDirectoryFil
 			// this cannot be hard coded with the .pkg file, this allows for
 			// dynamic handling of long names (due to an edge case).
 			if strings.Contains(relativePkgLow, pkgLowered) {
+				err := f.verify(file)
+				if err != nil {
+					fmt.Printf("Refused to install %s, the checksum failed to verify\n", pkg)
+					failedInstall = true
+					break
+				}
+
 				f.log.Info(fmt.Sprintf("Installing package %s", pkg))
 				fmt.Printf("Starting installation for %s\n", pkg)
 
-				cmd := fmt.Sprintf(`installer -pkg "%s" -target /`, file)
-				f.log.Debug(fmt.Sprintf("Package: %s | Package path: %s | Command: %s", pkg, file, cmd))
+				installCmd := runner.NewCmd("sudo", "installer", "-pkg", file, "-target", "/")
+				f.log.Debug(fmt.Sprintf("Package: %s | Package path: %s | Command: %v", pkg, file, installCmd.Args))
 
-				out, err := exec.Command("sudo", "bash", "-c", cmd).Output()
+				installCmd.OnLine = func(line string, stderr bool) {
+					f.logLine(pkg, line, stderr)
+				}
+
+				res, err := f.runner.Exec(installCmd)
 				if err != nil {
-					outStr := strings.TrimSpace(string(out))
-					f.log.Warn(fmt.Sprintf("Failed installation of %s: %s %v", pkg, outStr, err))
-					fmt.Printf("Failed to install %s", pkg)
+					errStr := strings.TrimSpace(string(res.Stderr))
+					f.log.Warn(fmt.Sprintf("Failed installation of %s: %s %v", pkg, errStr, err))
+					fmt.Printf("Failed to install %s\n", pkg)
 					failedInstall = true
 					break
 				}
@@ -234,6 +329,7 @@ func (f *FileHandler) InstallPackages(packagesPath []string, installDirectoryFil
 
 				successfulInstall = true
 				installedFiles += 1
+				f.installedPackages = append(f.installedPackages, pkg)
 				fmt.Printf("Installed %s\n", pkg)
 				break
 			}
@@ -244,11 +340,34 @@ func (f *FileHandler) InstallPackages(packagesPath []string, installDirectoryFil
 			f.log.Warn(fullFailMsg)
 			fmt.Println(fullFailMsg)
 		}
+
+		if !successfulInstall {
+			notInstalled[pkg] = true
+			f.failedPackages = append(f.failedPackages, pkg)
+		}
 	}
 
 	return installedFiles
 }
 
+// GetInstalledPackages returns the packages that were installed by InstallPackages.
+// Packages with an existing installation are not included.
+func (f *FileHandler) GetInstalledPackages() []string {
+	return slices.Clone(f.installedPackages)
+}
+
+// GetSkippedPackages returns the packages that were skipped by InstallPackages
+// due to an existing installation.
+func (f *FileHandler) GetSkippedPackages() []string {
+	return slices.Clone(f.skippedPackages)
+}
+
+// GetFailedPackages returns the packages that failed to install or could not be found
+// by InstallPackages.
+func (f *FileHandler) GetFailedPackages() []string {
+	return slices.Clone(f.failedPackages)
+}
+
 // GetPackages returns the packages that are being installed.
 // This does not include the installed files.
 func (f *FileHandler) GetPackages() []string {
@@ -261,8 +380,8 @@ func (f *FileHandler) GetPackages() []string {
 	return packages
 }
 
-// GetAllPackages gets the packages to be installed and its installed file names array.
-func (f *FileHandler) GetAllPackages() map[string][]string {
+// GetAllPackages gets the packages to be installed and their entries.
+func (f *FileHandler) GetAllPackages() map[string]yaml.Package {
 	return f.packagesToInstall
 }
 
@@ -271,14 +390,11 @@ func (f *FileHandler) GetAllPackages() map[string][]string {
 //
 // The folder will be the same name as the mounted DMG as displayed in the Volumes directory.
 func (f *FileHandler) AddDmgPackages(volumePaths []string, pkgDirectory string) {
-	cmd := "cp -r '%s' '%s'"
-
 	for _, volumePath := range volumePaths {
-		newCmd := fmt.Sprintf(cmd, volumePath, pkgDirectory)
 		f.log.Info(fmt.Sprintf("Copying files in path %s", volumePath))
 
 		// no sudo unless you want root to own it (not tested)
-		_, err := exec.Command("bash", "-c", newCmd).Output()
+		_, err := runner.Output(f.runner, "cp", "-r", volumePath, pkgDirectory)
 		if err != nil {
 			f.log.Warn(fmt.Sprintf("Failed to copy contents of %s: %v", volumePath, err))
 			continue
@@ -302,20 +418,21 @@ func (f *FileHandler) AddDmgPackages(volumePaths []string, pkgDirectory string)
 // Upon successful completion, an array of paths to the mount inside the Volumes
 // directory is returned.
 func (f *FileHandler) AttachDmgs(dmgPaths []string) []string {
-	cmd := "hdiutil attach '%s'"
-
 	volumePaths := make([]string, 0)
 
 	for _, dmgPath := range dmgPaths {
 		f.log.Debug(fmt.Sprintf("DMG path: %s", dmgPath))
 
 		if strings.Contains(dmgPath, ".dmg") {
-			newCmd := fmt.Sprintf(cmd, dmgPath)
+			err := f.verify(dmgPath)
+			if err != nil {
+				fmt.Printf("Refused to mount %s, the checksum failed to verify\n", filepath.Base(dmgPath))
+				continue
+			}
 
 			f.log.Info(fmt.Sprintf("Mounting %s", dmgPath))
-			f.log.Debug(fmt.Sprintf("Command: %s", newCmd))
 
-			out, err := exec.Command("bash", "-c", newCmd).Output()
+			out, err := runner.Output(f.runner, "hdiutil", "attach", dmgPath)
 			if err != nil {
 				f.log.Warn(fmt.Sprintf("Failed to mount %s: %v", dmgPath, err))
 				continue
@@ -325,8 +442,14 @@ func (f *FileHandler) AttachDmgs(dmgPaths []string) []string {
 
 			outArr := strings.Split(string(out), "\t")
 			volumePath := strings.TrimSpace(outArr[len(outArr)-1])
+			// no output is given during a dry run.
+			if volumePath == "" {
+				f.log.Warnf("No volume found for %s", dmgPath)
+				continue
+			}
 
 			volumePaths = append(volumePaths, volumePath)
+			f.mountedVolumes = append(f.mountedVolumes, volumePath)
 		}
 	}
 
@@ -335,12 +458,40 @@ func (f *FileHandler) AttachDmgs(dmgPaths []string) []string {
 	return volumePaths
 }
 
+// ScriptOptions are the options of a script execution.
+type ScriptOptions struct {
+	// Args are the arguments given to the script.
+	Args []string
+
+	// Env is the environment of the script, it replaces the environment of the binary.
+	// If nil, the environment of the binary is used.
+	Env []string
+
+	// Timeout is the time limit of the script. Zero uses the time limit of the commands.
+	Timeout time.Duration
+
+	// RunAs is the user that runs the script with sudo, "root" runs the script as root.
+	// If empty, the script runs as the user running the binary.
+	RunAs string
+
+	// ExitCodes are the exit codes of a successful run. If empty, only 0 is successful.
+	ExitCodes []int
+}
+
+// ScriptOutput is the output of a script execution.
+type ScriptOutput struct {
+	Stdout string
+	Stderr string
+}
+
 // ExecuteScripts runs shell scripts on the device.
-// This requires the script file name and an array of paths containing shell scripts.
+// This requires the script file name, an array of paths containing shell scripts
+// and the options of the script.
 //
-// It returns a string and an error, depending on the exit status of the script.
+// The output of the script is logged line by line while it runs, with the script name as prefix.
+// It returns the output and an error, depending on the exit status of the script.
 // If the script did not get executed, an error is returned.
-func (f *FileHandler) ExecuteScript(scriptName string, scriptPaths []string) (string, error) {
+func (f *FileHandler) ExecuteScript(scriptName string, scriptPaths []string, opts ScriptOptions) (ScriptOutput, error) {
 	f.log.Infof("Starting script execution for %s", scriptName)
 
 	ogName := scriptName // only used for logging
@@ -357,7 +508,7 @@ func (f *FileHandler) ExecuteScript(scriptName string, scriptPaths []string) (st
 			f.log.Info(fmt.Sprintf("Found %s in cache", ogName))
 
 			scriptPath := f.scriptsPathCache[scriptName]
-			outMsg, err := f.execute(scriptPath)
+			outMsg, err := f.execute(scriptPath, opts)
 			if err != nil {
 				return outMsg, err
 			}
@@ -370,9 +521,9 @@ func (f *FileHandler) ExecuteScript(scriptName string, scriptPaths []string) (st
 
 		// substring match
 		if strings.Contains(scriptPathLow, scriptName) {
-			outMsg, err := f.execute(scriptPath)
+			outMsg, err := f.execute(scriptPath, opts)
 
-			f.log.Debugf("Script '%s' output: %s, error: %v", ogName, outMsg, err)
+			f.log.Debugf("Script '%s' finished, error: %v", ogName, err)
 			if err != nil {
 				return outMsg, err
 			}
@@ -381,21 +532,146 @@ func (f *FileHandler) ExecuteScript(scriptName string, scriptPaths []string) (st
 		}
 	}
 
-	return "", errors.New("failed to find script")
+	return ScriptOutput{}, errors.New("failed to find script")
+}
+
+// execute executes the given script path with the options.
+//
+// It returns the output of the script and an error, if one occurred.
+func (f *FileHandler) execute(scriptPath string, opts ScriptOptions) (ScriptOutput, error) {
+	f.log.Debugf("Script %s arguments: %v", scriptPath, opts.Args)
+
+	err := f.verify(scriptPath)
+	if err != nil {
+		return ScriptOutput{}, err
+	}
+
+	// the path is given as $0 to keep the arguments out of the command string.
+	return f.runBash(`"$0" "$@"`, scriptPath, opts)
 }
 
-// execute executes the given script path.
+// ExecuteInlineScript runs the body of a script given in the config. The script name is
+// given to the script as $0 and is used for logging.
 //
 // It returns the output of the script and an error, if one occurred.
-func (f *FileHandler) execute(scriptPath string) (string, error) {
-	// NOTE: if the user exits non-zero on their script, this will fail.
-	out, err := exec.Command("bash", "-c", scriptPath).Output()
-	outMsg := strings.TrimSpace(string(out))
+func (f *FileHandler) ExecuteInlineScript(scriptName string, body string, opts ScriptOptions) (ScriptOutput, error) {
+	f.log.Infof("Starting inline script execution for %s", scriptName)
+	f.log.Debugf("Script %s arguments: %v", scriptName, opts.Args)
+
+	outMsg, err := f.runBash(body, scriptName, opts)
+	f.log.Debugf("Script '%s' finished, error: %v", scriptName, err)
+
+	return outMsg, err
+}
+
+// runBash runs the command string with bash and the options of the script.
+// The name is given as $0 and the arguments of the options after it.
+//
+// The output is logged line by line while the script runs.
+func (f *FileHandler) runBash(command string, scriptName string, opts ScriptOptions) (ScriptOutput, error) {
+	name := "bash"
+	args := append([]string{"-c", command, scriptName}, opts.Args...)
+
+	// the environment is kept, it only contains the context of the deployment.
+	if opts.RunAs == "root" {
+		name = "sudo"
+		args = append([]string{"-n", "-E", "bash"}, args...)
+	} else if opts.RunAs != "" {
+		name = "sudo"
+		args = append([]string{"-n", "-E", "-u", opts.RunAs, "bash"}, args...)
+	}
+
+	prefix := filepath.Base(scriptName)
+	cmd := runner.NewCmd(name, args...)
+	cmd.Env = opts.Env
+	cmd.Timeout = opts.Timeout
+	cmd.OnLine = func(line string, stderr bool) {
+		f.logLine(prefix, line, stderr)
+	}
+
+	res, err := f.runner.Exec(cmd)
+	out := ScriptOutput{
+		Stdout: strings.TrimSpace(string(res.Stdout)),
+		Stderr: strings.TrimSpace(string(res.Stderr)),
+	}
+
+	return out, checkExitCode(err, opts.ExitCodes)
+}
+
+// logLine logs a line of the output of a running command with the prefix.
+// The lines are shown in the terminal with the verbose output.
+func (f *FileHandler) logLine(prefix string, line string, stderr bool) {
+	if stderr {
+		f.log.Infof("[%s:stderr] %s", prefix, line)
+		return
+	}
+
+	f.log.Infof("[%s] %s", prefix, line)
+}
+
+// checkExitCode returns nil if the exit code of the error is one of the successful
+// exit codes. If the exit codes are empty, only 0 is successful.
+//
+// An error without an exit code, such as a time limit, is always returned.
+func checkExitCode(err error, exitCodes []int) error {
+	if len(exitCodes) == 0 {
+		return err
+	}
+
+	code := runner.ExitCode(err)
+	if code < 0 {
+		return err
+	}
+
+	if slices.Contains(exitCodes, code) {
+		return nil
+	}
+	if err == nil {
+		return fmt.Errorf("exit status %d is not one of the exit codes %v", code, exitCodes)
+	}
+
+	return fmt.Errorf("%w, expected exit codes %v", err, exitCodes)
+}
+
+// SetChecksums sets the SHA-256 checksums of the files by the file name. The packages,
+// DMGs, apps and script files with a checksum are verified before they are used,
+// the file names are not case sensitive.
+func (f *FileHandler) SetChecksums(checksums map[string]string) {
+	f.checksums = make(map[string]string, len(checksums))
+
+	for name, sum := range checksums {
+		f.checksums[strings.ToLower(strings.TrimSpace(name))] = sum
+	}
+}
+
+// verify verifies the checksum of the file if it has one. The file must not be used
+// if an error is returned, the error is kept for the report.
+func (f *FileHandler) verify(path string) error {
+	expected, ok := f.checksums[strings.ToLower(filepath.Base(path))]
+	if !ok {
+		return nil
+	}
+
+	err := checksum.Verify(path, expected)
 	if err != nil {
-		return outMsg, err
+		var verifyErr *checksum.VerifyError
+		if errors.As(err, &verifyErr) {
+			f.checksumErrors = append(f.checksumErrors, verifyErr)
+		}
+
+		f.log.Warnf("Refused %s: %v", path, err)
+		return err
 	}
 
-	return outMsg, nil
+	f.log.Infof("Verified checksum of %s", path)
+
+	return nil
+}
+
+// GetChecksumErrors returns the errors of the files that were refused due to a
+// failed checksum verification.
+func (f *FileHandler) GetChecksumErrors() []*checksum.VerifyError {
+	return slices.Clone(f.checksumErrors)
 }
 
 // GetScriptCache returns the map of the script cache.
@@ -406,25 +682,29 @@ func (f *FileHandler) GetScriptCache() map[string]string {
 // DetachDmgs detaches the DMG from the Volumes directory.
 // The paths are obtained from AttachDmgs.
 func (f *FileHandler) DetachDmgs(volumePaths []string) {
-	cmd := "hdiutil detach '%s'"
-
 	for _, volumePath := range volumePaths {
 		f.log.Info(fmt.Sprintf("Unmounting %s", volumePath))
 		f.log.Debug(fmt.Sprintf("Mount: %s", volumePath))
 
-		newCmd := fmt.Sprintf(cmd, volumePath)
-		f.log.Debug(fmt.Sprintf("Command: %s", newCmd))
-
-		out, err := exec.Command("bash", "-c", newCmd).Output()
+		out, err := runner.Output(f.runner, "hdiutil", "detach", volumePath)
 		if err != nil {
 			f.log.Warn(fmt.Sprintf("Manual interaction needed, failed to unmount %s: %v", volumePath, err))
 			continue
 		}
 
 		f.log.Debug(fmt.Sprintf("Command output: %s", strings.TrimSpace(string(out))))
+
+		f.mountedVolumes = slices.DeleteFunc(f.mountedVolumes, func(mount string) bool {
+			return mount == volumePath
+		})
 	}
 }
 
+// MountedVolumes returns the volumes attached by AttachDmgs that have not been detached.
+func (f *FileHandler) MountedVolumes() []string {
+	return slices.Clone(f.mountedVolumes)
+}
+
 // CopyFiles recursively copies an array of directory paths to a target directory.
 //
 // Errors during the copy operation are logged and skipped, requiring manual intervention.
@@ -444,6 +724,17 @@ func (f *FileHandler) CopyFiles(paths []string, target string) {
 
 		f.log.Debug(fmt.Sprintf("Target file: %s", targetFile))
 
+		err = f.verify(path)
+		if err != nil {
+			fmt.Printf("Refused to copy %s, the checksum failed to verify\n", file.Name())
+			continue
+		}
+
+		if f.runner.DryRun() {
+			f.runner.Planf("copy %s to %s", path, targetFile)
+			continue
+		}
+
 		if file.IsDir() {
 			// copyFS already creates the directories if missing
 			err = os.CopyFS(targetFile, os.DirFS(path))
@@ -479,6 +770,25 @@ func (f *FileHandler) CopyFiles(paths []string, target string) {
 	}
 }
 
+// isPackageInstalled returns true if the package has an existing installation. A package
+// with receipts must have its receipts installed, and a package with a bundle identifier must
+// have its app installed, see hasReceipts and hasApp. Otherwise the installed file names are
+// searched, see IsInstalled.
+func (f *FileHandler) isPackageInstalled(name string, pkg yaml.Package, installDirectoryFiles []string) bool {
+	if len(pkg.Receipts) == 0 && pkg.BundleID == "" {
+		return f.IsInstalled(pkg.Installed, installDirectoryFiles)
+	}
+
+	if len(pkg.Receipts) > 0 && !f.hasReceipts(name, pkg) {
+		return false
+	}
+	if pkg.BundleID != "" && !f.hasApp(name, pkg) {
+		return false
+	}
+
+	return true
+}
+
 // IsInstalled searches for the names of an installed package in the search directory.
 //
 // If an installed package name is found in the search directory, true is returned indicating
@@ -529,11 +839,14 @@ func (p *FileHandler) PackageString() string {
 
 	for key, val := range p.packagesToInstall {
 		installationFiles := "No installed files given"
-		if len(val) > 0 {
-			installationFiles = strings.Join(val, ",")
+		if len(val.Installed) > 0 {
+			installationFiles = strings.Join(val.Installed, ",")
 		}
 
 		str := fmt.Sprintf("%s+%s", key, installationFiles)
+		if val.BundleID != "" {
+			str = fmt.Sprintf("%s+%s>=%s", str, val.BundleID, val.MinVersion)
+		}
 
 		strSlice = append(strSlice, str)
 	}
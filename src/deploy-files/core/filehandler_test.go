@@ -2,17 +2,24 @@ package core
 
 import (
 	"bytes"
+	"errors"
 	"fmt"
 	"log"
 	"maps"
 	"math/rand"
 	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
 	"strconv"
 	"strings"
 	"testing"
 	"time"
 
+	"github.com/bobllor/macdeploy/src/deploy-files/checksum"
 	"github.com/bobllor/macdeploy/src/deploy-files/logger"
+	"github.com/bobllor/macdeploy/src/deploy-files/runner"
+	"github.com/bobllor/macdeploy/src/deploy-files/yaml"
 	"github.com/bobllor/macdeploy/src/tests"
 )
 
@@ -21,9 +28,9 @@ var packagesToAdd []string = []string{
 	"AnoTherCaseSenSITIVE.pkg",
 }
 
-var packagesToInstall = map[string][]string{
-	"TeamViewer.pkg":   {"teamviewer"},
-	"Test packAGe.PkG": {"test.package"},
+var packagesToInstall = map[string]yaml.Package{
+	"TeamViewer.pkg":   {Installed: []string{"teamviewer"}},
+	"Test packAGe.PkG": {Installed: []string{"test.package"}},
 }
 
 var searchDirectoryFiles = []string{
@@ -34,7 +41,7 @@ var searchDirectoryFiles = []string{
 var baseLenPkgInstall int = len(packagesToAdd)
 
 func TestArrayLowerCase(t *testing.T) {
-	handler := NewFileHandler(tests.TestLogger)
+	handler := NewFileHandler(tests.TestLogger, runner.New())
 
 	handler.AddPackages(packagesToAdd)
 
@@ -60,7 +67,7 @@ func TestArrayLowerCase(t *testing.T) {
 }
 
 func TestAddPackages(t *testing.T) {
-	handler := NewFileHandler(tests.TestLogger)
+	handler := NewFileHandler(tests.TestLogger, runner.New())
 
 	handler.AddPackages(packagesToAdd)
 
@@ -78,7 +85,7 @@ func TestAddPackages(t *testing.T) {
 
 func TestRemovePackagesExactName(t *testing.T) {
 	log := logger.NewLogger(log.New(bytes.NewBuffer([]byte{}), "", log.Ldate), logger.Ldebug)
-	handler := NewFileHandler(log)
+	handler := NewFileHandler(log, runner.New())
 
 	packagesCopy := make([]string, len(packagesToAdd))
 	copy(packagesCopy, packagesToAdd)
@@ -103,7 +110,7 @@ func TestRemovePackagesExactName(t *testing.T) {
 }
 
 func TestRemovePackagesWithSubstring(t *testing.T) {
-	handler := NewFileHandler(tests.TestLogger)
+	handler := NewFileHandler(tests.TestLogger, runner.New())
 
 	packagesCopy := make([]string, len(packagesToAdd))
 	copy(packagesCopy, packagesToAdd)
@@ -127,7 +134,7 @@ func TestRemovePackagesWithSubstring(t *testing.T) {
 }
 
 func TestRemovePackagesNoMatch(t *testing.T) {
-	handler := NewFileHandler(tests.TestLogger)
+	handler := NewFileHandler(tests.TestLogger, runner.New())
 
 	handler.AddPackages(packagesToAdd)
 
@@ -142,13 +149,13 @@ func TestRemovePackagesNoMatch(t *testing.T) {
 }
 
 func TestInstalledPackagesNormal(t *testing.T) {
-	handler := NewFileHandler(tests.TestLogger)
+	handler := NewFileHandler(tests.TestLogger, runner.New())
 
 	alreadyInstalledCount := 0
 
 	handler.AddMapPackages(packagesToInstall)
-	for _, installedNames := range packagesToInstall {
-		if handler.IsInstalled(installedNames, searchDirectoryFiles) {
+	for _, pkg := range packagesToInstall {
+		if handler.IsInstalled(pkg.Installed, searchDirectoryFiles) {
 			alreadyInstalledCount += 1
 		}
 	}
@@ -162,7 +169,7 @@ func TestInstallPackagesAddNewPackages(t *testing.T) {
 	projectDirectory := t.TempDir()
 
 	log := logger.NewLogger(log.New(bytes.NewBuffer([]byte{}), "", log.Ldate), logger.Ldebug)
-	handler := NewFileHandler(log)
+	handler := NewFileHandler(log, runner.New())
 
 	handler.AddMapPackages(packagesToInstall)
 	handler.AddPackages(packagesToAdd)
@@ -171,8 +178,8 @@ func TestInstallPackagesAddNewPackages(t *testing.T) {
 	expectedLen := len(packagesToAdd)
 
 	// creating the pkg files in the temp folder
-	for pkg, installedNames := range handler.GetAllPackages() {
-		isInstalled := handler.IsInstalled(installedNames, searchDirectoryFiles)
+	for pkg, entry := range handler.GetAllPackages() {
+		isInstalled := handler.IsInstalled(entry.Installed, searchDirectoryFiles)
 		if !isInstalled {
 			installedCount += 1
 
@@ -199,13 +206,51 @@ func TestInstallPackagesAddNewPackages(t *testing.T) {
 }
 
 func TestInstallPackagesNoPackages(t *testing.T) {
-	handler := NewFileHandler(tests.TestLogger)
+	handler := NewFileHandler(tests.TestLogger, runner.New())
 
 	count := handler.InstallPackages([]string{}, []string{})
 
 	tests.Checkf(t, count != 0, "installed count expected to be 0, got %d", count)
 }
 
+func TestInstallPackagesOrder(t *testing.T) {
+	fake := runner.NewFake()
+	fake.Respond(runner.Response{Match: "installer -pkg /tmp/dist/runtime.pkg", ExitCode: 1})
+
+	handler := NewFileHandler(tests.TestLogger, fake)
+	handler.AddMapPackages(map[string]yaml.Package{
+		"Agent-Config.pkg": {DependsOn: []string{"agent.pkg"}},
+		"agent.pkg":        {DependsOn: []string{"runtime.pkg"}},
+		"runtime.pkg":      {},
+		"zoom.pkg":         {},
+		"browser.pkg":      {Order: 1},
+	})
+
+	packages := []string{
+		"/tmp/dist/agent-config.pkg", "/tmp/dist/agent.pkg", "/tmp/dist/runtime.pkg",
+		"/tmp/dist/zoom.pkg", "/tmp/dist/browser.pkg",
+	}
+	installed := handler.InstallPackages(packages, []string{})
+
+	if installed != 2 {
+		t.Fatalf("expected 2 installed packages, got %d", installed)
+	}
+
+	expected := []string{
+		"sudo installer -pkg /tmp/dist/runtime.pkg -target /",
+		"sudo installer -pkg /tmp/dist/zoom.pkg -target /",
+		"sudo installer -pkg /tmp/dist/browser.pkg -target /",
+	}
+	if !slices.Equal(fake.Commands(), expected) {
+		t.Fatalf("unexpected install order: %v", fake.Commands())
+	}
+
+	failed := handler.GetFailedPackages()
+	if !slices.Equal(failed, []string{"runtime.pkg", "agent.pkg", "agent-config.pkg"}) {
+		t.Fatalf("expected the dependents to fail with runtime.pkg, got %v", failed)
+	}
+}
+
 func TestReadDmg(t *testing.T) {
 	projectDirectory := t.TempDir()
 
@@ -224,7 +269,7 @@ func TestReadDmg(t *testing.T) {
 		}
 	}
 
-	dmg := NewFileHandler(log)
+	dmg := NewFileHandler(log, runner.New())
 
 	dmg.AddMapPackages(packagesToInstall)
 	dmgFiles, err := dmg.ReadDir(projectDirectory, ".dmg")
@@ -244,7 +289,7 @@ func TestCopyApp(t *testing.T) {
 
 	log := logger.NewLogger(log.New(bytes.NewBuffer([]byte{}), "", log.Ldate), logger.Ldebug)
 
-	handler := NewFileHandler(log)
+	handler := NewFileHandler(log, runner.New())
 
 	handler.AddMapPackages(packagesToInstall)
 	appBundle := "a program bundle.app"
@@ -291,7 +336,7 @@ func TestCopyFile(t *testing.T) {
 
 	log := logger.NewLogger(log.New(bytes.NewBuffer([]byte{}), "", log.Ldate), logger.Ldebug)
 
-	handler := NewFileHandler(log)
+	handler := NewFileHandler(log, runner.New())
 
 	handler.AddMapPackages(packagesToInstall)
 	newTestDir := projectDirectory + "/" + "test-dir"
@@ -332,7 +377,7 @@ func TestScriptCacheAddition(t *testing.T) {
 	projectDirectory := t.TempDir()
 
 	log := logger.NewLogger(log.New(bytes.NewBuffer([]byte{}), "", log.Ldate), logger.Ldebug)
-	handler := NewFileHandler(log)
+	handler := NewFileHandler(log, runner.New())
 
 	handler.AddMapPackages(packagesToInstall)
 	fakeScriptFiles := []string{
@@ -352,7 +397,7 @@ func TestScriptCacheAddition(t *testing.T) {
 	}
 
 	for _, file := range fakeScriptFiles {
-		_, err := handler.ExecuteScript(file, scriptPaths)
+		_, err := handler.ExecuteScript(file, scriptPaths, ScriptOptions{})
 		tests.Fatal(t, err, fmt.Sprintf("Script %s failed: %v", file, err))
 	}
 
@@ -372,7 +417,7 @@ func TestScriptExecution(t *testing.T) {
 	projectDirectory := t.TempDir()
 
 	log := logger.NewLogger(log.New(bytes.NewBuffer([]byte{}), "", log.Ldate), logger.Ldebug)
-	handler := NewFileHandler(log)
+	handler := NewFileHandler(log, runner.New())
 
 	handler.AddMapPackages(packagesToInstall)
 	baseScriptNames := []string{
@@ -402,26 +447,54 @@ func TestScriptExecution(t *testing.T) {
 	}
 
 	for _, execScript := range executingScripts {
-		out, err := handler.ExecuteScript(execScript, scriptPaths)
+		out, err := handler.ExecuteScript(execScript, scriptPaths, ScriptOptions{})
 		if err != nil {
 			t.Fatal(err)
 		}
 
-		if out != msg {
-			t.Fatalf("got %s expected %s", out, msg)
+		if out.Stdout != msg {
+			t.Fatalf("got %s expected %s", out.Stdout, msg)
 		}
 	}
 }
 
+func TestScriptOptions(t *testing.T) {
+	projectDirectory := t.TempDir()
+
+	handler := NewFileHandler(tests.TestLogger, runner.New())
+
+	scriptContent := "#!/usr/bin/env bash\necho \"$MACDEPLOY_STAGE $1|$2\""
+	err := os.WriteFile(projectDirectory+"/args.sh", []byte(scriptContent), 0o755)
+	tests.Checkf(t, err != nil, "failed to write script: %v", err)
+
+	scriptPaths, err := handler.ReadDir(projectDirectory, ".sh")
+	tests.Checkf(t, err != nil, "failed to read directory: %v", err)
+
+	opts := ScriptOptions{
+		Args: []string{"one two", "$HOME"},
+		Env:  []string{"PATH=" + os.Getenv("PATH"), "MACDEPLOY_STAGE=post_scripts"},
+	}
+
+	out, err := handler.ExecuteScript("args.sh", scriptPaths, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := "post_scripts one two|$HOME"
+	if out.Stdout != expected {
+		t.Fatalf("got %s expected %s", out.Stdout, expected)
+	}
+}
+
 func TestPackageString(t *testing.T) {
-	handler := NewFileHandler(tests.TestLogger)
+	handler := NewFileHandler(tests.TestLogger, runner.New())
 
 	pkg := "chrome.pkg"
 	installFiles := "chrome.app,google chrome.app"
 
 	copyPkgToInstall := maps.Clone(packagesToInstall)
 
-	copyPkgToInstall[pkg] = strings.Split(installFiles, ",")
+	copyPkgToInstall[pkg] = yaml.Package{Installed: strings.Split(installFiles, ",")}
 
 	handler.AddMapPackages(copyPkgToInstall)
 
@@ -429,7 +502,7 @@ func TestPackageString(t *testing.T) {
 
 	for key, val := range copyPkgToInstall {
 		// installation packages are separated by a comma
-		valStr := strings.ToLower(strings.Join(val, ","))
+		valStr := strings.ToLower(strings.Join(val.Installed, ","))
 		key = strings.ToLower(key)
 
 		tests.Checkf(t, strings.Contains(str, key) == false, "failed to find %s in %s", key, str)
@@ -438,7 +511,7 @@ func TestPackageString(t *testing.T) {
 }
 
 func TestPackageStringNoInstallFiles(t *testing.T) {
-	handler := NewFileHandler(tests.TestLogger)
+	handler := NewFileHandler(tests.TestLogger, runner.New())
 
 	pkg := "chrome.pkg"
 
@@ -456,3 +529,184 @@ func TestPackageStringNoInstallFiles(t *testing.T) {
 		tests.Checkf(t, strings.Contains(str, val) == false, "string %s not found in %s", val, str)
 	}
 }
+
+func TestCheckExitCode(t *testing.T) {
+	exitErr := exec.Command("bash", "-c", "exit 2").Run()
+
+	cases := []struct {
+		err       error
+		exitCodes []int
+		ok        bool
+	}{
+		{nil, nil, true},
+		{exitErr, nil, false},
+		{exitErr, []int{0, 2}, true},
+		{exitErr, []int{0, 1}, false},
+		{nil, []int{2}, false},
+		{errors.New("command timed out"), []int{0, 2}, false},
+	}
+
+	for i, c := range cases {
+		err := checkExitCode(c.err, c.exitCodes)
+		if (err == nil) != c.ok {
+			t.Fatalf("case %d: got error %v, expected success %t", i, err, c.ok)
+		}
+	}
+}
+
+func TestInlineScript(t *testing.T) {
+	handler := NewFileHandler(tests.TestLogger, runner.New())
+
+	body := "echo \"$0 $1\"\necho warning >&2\necho \"$MACDEPLOY_STAGE\""
+	opts := ScriptOptions{
+		Args: []string{"one"},
+		Env:  []string{"MACDEPLOY_STAGE=mid_scripts"},
+	}
+
+	out, err := handler.ExecuteInlineScript("set_default", body, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := "set_default one\nmid_scripts"
+	if out.Stdout != expected {
+		t.Fatalf("got %s expected %s", out.Stdout, expected)
+	}
+	if out.Stderr != "warning" {
+		t.Fatalf("got %s expected stderr warning", out.Stderr)
+	}
+}
+
+func TestInstallPackagesRunner(t *testing.T) {
+	fake := runner.NewFake()
+	fake.Respond(runner.Response{Match: "broken.pkg", Stderr: "installer: failed\n", ExitCode: 1})
+
+	handler := NewFileHandler(tests.TestLogger, fake)
+	handler.AddPackages([]string{"chrome.pkg", "broken.pkg"})
+
+	packages := []string{"/tmp/dist/chrome.pkg", "/tmp/dist/broken.pkg"}
+	installed := handler.InstallPackages(packages, []string{})
+
+	if installed != 1 {
+		t.Fatalf("got %d installed packages, expected 1", installed)
+	}
+	if strings.Join(handler.GetInstalledPackages(), ",") != "chrome.pkg" {
+		t.Fatalf("got installed packages %v", handler.GetInstalledPackages())
+	}
+	if !fake.Ran("sudo installer -pkg /tmp/dist/chrome.pkg -target /") {
+		t.Fatalf("installer not ran: %v", fake.Commands())
+	}
+}
+
+func TestInstallPackagesQuotedPath(t *testing.T) {
+	fake := runner.NewFake()
+
+	pkg := `it's "a" $pkg.pkg`
+	handler := NewFileHandler(tests.TestLogger, fake)
+	handler.AddPackages([]string{pkg})
+
+	path := "/tmp/dist dir/" + pkg
+	installed := handler.InstallPackages([]string{path}, []string{})
+	if installed != 1 {
+		t.Fatalf("got %d installed packages, expected 1", installed)
+	}
+
+	expected := []string{"installer", "-pkg", path, "-target", "/"}
+	args := fake.Calls()[0].Args
+	if !slices.Equal(args, expected) {
+		t.Fatalf("got arguments %q, expected %q", args, expected)
+	}
+}
+
+func TestDmgQuotedPath(t *testing.T) {
+	fake := runner.NewFake()
+	fake.On("hdiutil attach", "/dev/disk4s1\tApple_HFS\t/Volumes/It's $App\n")
+
+	handler := NewFileHandler(tests.TestLogger, fake)
+
+	dmg := `/tmp/dist/It's "$App".dmg`
+	volumes := handler.AttachDmgs([]string{dmg})
+	if !slices.Equal(volumes, []string{"/Volumes/It's $App"}) {
+		t.Fatalf("got volumes %q", volumes)
+	}
+
+	handler.DetachDmgs(volumes)
+
+	calls := fake.Calls()
+	if !slices.Equal(calls[0].Args, []string{"attach", dmg}) {
+		t.Fatalf("got attach arguments %q", calls[0].Args)
+	}
+	if !slices.Equal(calls[1].Args, []string{"detach", volumes[0]}) {
+		t.Fatalf("got detach arguments %q", calls[1].Args)
+	}
+	if len(handler.MountedVolumes()) != 0 {
+		t.Fatalf("volumes not detached: %v", handler.MountedVolumes())
+	}
+}
+
+func TestInstallPackagesChecksum(t *testing.T) {
+	fake := runner.NewFake()
+	dir := t.TempDir()
+
+	for _, pkg := range []string{"chrome.pkg", "tampered.pkg"} {
+		err := os.WriteFile(filepath.Join(dir, pkg), []byte(pkg), 0o644)
+		if err != nil {
+			t.Fatalf("failed to write package: %v", err)
+		}
+	}
+
+	chromeSum, err := checksum.Sum(filepath.Join(dir, "chrome.pkg"))
+	if err != nil {
+		t.Fatalf("failed to sum package: %v", err)
+	}
+
+	handler := NewFileHandler(tests.TestLogger, fake)
+	handler.AddPackages([]string{"chrome.pkg", "tampered.pkg"})
+	handler.SetChecksums(map[string]string{
+		"Chrome.pkg":   chromeSum,
+		"tampered.pkg": strings.Repeat("a", 64),
+	})
+
+	packages := []string{filepath.Join(dir, "chrome.pkg"), filepath.Join(dir, "tampered.pkg")}
+	installed := handler.InstallPackages(packages, []string{})
+
+	if installed != 1 {
+		t.Fatalf("got %d installed packages, expected 1", installed)
+	}
+	if strings.Join(handler.GetFailedPackages(), ",") != "tampered.pkg" {
+		t.Fatalf("got failed packages %v", handler.GetFailedPackages())
+	}
+	if fake.Ran("tampered.pkg") {
+		t.Fatalf("installer ran with a failed checksum: %v", fake.Commands())
+	}
+
+	checksumErrors := handler.GetChecksumErrors()
+	if len(checksumErrors) != 1 || checksumErrors[0].Actual == "" {
+		t.Fatalf("got checksum errors %v", checksumErrors)
+	}
+}
+
+func TestExecuteScriptChecksum(t *testing.T) {
+	fake := runner.NewFake()
+	dir := t.TempDir()
+
+	scriptPath := filepath.Join(dir, "setup.sh")
+	err := os.WriteFile(scriptPath, []byte("echo setup"), 0o755)
+	if err != nil {
+		t.Fatalf("failed to write script: %v", err)
+	}
+
+	handler := NewFileHandler(tests.TestLogger, fake)
+	handler.SetChecksums(map[string]string{"setup.sh": strings.Repeat("a", 64)})
+
+	_, err = handler.ExecuteScript("setup.sh", []string{scriptPath}, ScriptOptions{})
+	if err == nil {
+		t.Fatal("expected error from a script with a failed checksum")
+	}
+	if len(fake.Calls()) != 0 {
+		t.Fatalf("script ran with a failed checksum: %v", fake.Commands())
+	}
+	if len(handler.GetChecksumErrors()) != 1 {
+		t.Fatalf("got checksum errors %v", handler.GetChecksumErrors())
+	}
+}
@@ -1,12 +1,13 @@
 package core
 
 import (
+	"encoding/xml"
 	"errors"
 	"fmt"
-	"os/exec"
 	"strings"
 
 	"github.com/bobllor/macdeploy/src/deploy-files/logger"
+	"github.com/bobllor/macdeploy/src/deploy-files/runner"
 	"github.com/bobllor/macdeploy/src/deploy-files/scripts"
 	"github.com/bobllor/macdeploy/src/deploy-files/yaml"
 )
@@ -15,13 +16,15 @@ type FileVault struct {
 	admin  yaml.UserInfo
 	script *scripts.BashScripts
 	log    *logger.Logger
+	runner runner.Runner
 }
 
-func NewFileVault(admin yaml.UserInfo, script *scripts.BashScripts, log *logger.Logger) *FileVault {
+func NewFileVault(admin yaml.UserInfo, script *scripts.BashScripts, log *logger.Logger, runner runner.Runner) *FileVault {
 	fv := FileVault{
 		admin:  admin,
 		script: script,
 		log:    log,
+		runner: runner,
 	}
 
 	return &fv
@@ -34,8 +37,7 @@ func (f *FileVault) Enable(adminUser string, adminPassword string) string {
 
 	f.log.Info("Starting FileVault process")
 
-	out, err := exec.Command("sudo", "bash", "-c", f.script.EnableFileVault,
-		adminUser, adminPassword).CombinedOutput()
+	out, err := f.fdesetup(adminUser, adminPassword, "enable")
 	outText := strings.TrimSpace(string(out))
 	if err != nil {
 		f.log.Warnf("Failed to enable FileVault: %v", err)
@@ -59,9 +61,7 @@ func (f *FileVault) Enable(adminUser string, adminPassword string) string {
 //
 // If an error occurs it will be logged but the return will be an empty string.
 func (f *FileVault) ChangeRecovery(adminUser, adminPassword string) string {
-	out, err := exec.Command("sudo", "bash", "-c",
-		f.script.ChangeFileVaultKey, adminUser, adminPassword,
-	).CombinedOutput()
+	out, err := f.fdesetup(adminUser, adminPassword, "changerecovery", "-personal")
 	if err != nil {
 		f.log.Warnf("Failed to change recovery key: %v", err)
 		return ""
@@ -94,8 +94,7 @@ func (f *FileVault) Disable(adminUser string, adminPassword string) (bool, error
 		return false, nil
 	}
 
-	out, err := exec.Command("sudo", "bash", "-c", f.script.DisableFileVault,
-		adminUser, adminPassword).CombinedOutput()
+	out, err := f.fdesetup(adminUser, adminPassword, "disable")
 	outText := string(out)
 	if err != nil || strings.Contains(outText, "Error") {
 		f.log.Warnf("Failed to disable FileVault: %v", err)
@@ -115,12 +114,13 @@ func (f *FileVault) Disable(adminUser string, adminPassword string) (bool, error
 //
 // If the command failed to run then return an error.
 func (f *FileVault) Status() (bool, error) {
-	cmd := fmt.Sprintf("sudo -S fdesetup isactive <<< '%s'", f.admin.Password)
+	cmd := runner.NewCmd("sudo", "-S", "fdesetup", "isactive")
+	cmd.Stdin = f.admin.Password + "\n"
 	// turns out if isactive == false the exit status is 1. ignoring the error here!
-	out, _ := exec.Command("bash", "-c", cmd).Output()
+	res, _ := f.runner.Exec(cmd)
 
 	// instead of a boolean it must be in a string due to the subprocess.
-	fileVaultStatus := strings.TrimSpace(strings.ToLower(string(out)))
+	fileVaultStatus := strings.TrimSpace(strings.ToLower(string(res.Stdout)))
 
 	// either some fail happened or this is ran on a non-mac OS
 	if fileVaultStatus == "" {
@@ -143,9 +143,9 @@ func (f *FileVault) Status() (bool, error) {
 // user on the device, otherwise issues will occur due to FileVault.
 func (f *FileVault) AddSecureToken(username string, userPassword string) error {
 	// turns out i forgot secure token access... that was rough to find out in prod
-	secureTokenCmd := fmt.Sprintf(
-		"sudo sysadminctl -secureTokenOn '%s' -password '%s' -adminUser '%s' -adminPassword '%s'",
-		username, userPassword, f.admin.Username, f.admin.Password)
+	// SecureTokenScript takes 2 arguments, the passwords are read from stdin.
+	secureTokenCmd := runner.NewCmd("sudo", "bash", "-c", f.script.SecureToken, username, f.admin.Username)
+	secureTokenCmd.Stdin = fmt.Sprintf("%s\n%s\n", userPassword, f.admin.Password)
 
 	f.log.Debugf("Ran secure token command for user %s", username)
 
@@ -155,20 +155,19 @@ func (f *FileVault) AddSecureToken(username string, userPassword string) error {
 	// the user's password, user's username, and the admin username are not the point of failure.
 	// the point of failure is the admin password, because this can either be wrong from the config
 	// or the terminal input was wrong.
-	err := f.admin.ResetSudo()
-	if err != nil {
-		f.log.Warnf("Failed to run sudo reset command: %v", err)
-		return err
-	}
-
-	err = f.admin.InitializeSudo()
+	err := f.admin.VerifySudo(f.runner)
 	if err != nil {
 		f.log.Warnf("Error enabling token for user, manual interaction needed: %v", err)
 		f.log.Warn("Admin password is likely incorrect")
 		return err
 	}
 
-	_ = exec.Command("bash", "-c", secureTokenCmd).Run()
+	res, err := f.runner.Exec(secureTokenCmd)
+	if err != nil {
+		errStr := strings.TrimSpace(string(res.Stderr))
+		f.log.Warnf("Failed to add secure token for %s: %s %v", username, errStr, err)
+		return fmt.Errorf("failed to add secure token for %s: %w", username, err)
+	}
 	f.log.Infof("Secure token added for %s", username)
 
 	return nil
@@ -178,8 +177,7 @@ func (f *FileVault) AddSecureToken(username string, userPassword string) error {
 // are allowed to unlock the encrypted drive.
 // It will return the output string of the command, or an error if one occurs.
 func (f *FileVault) List() (string, error) {
-	cmd := "sudo fdesetup list"
-	out, err := exec.Command("bash", "-c", cmd).Output()
+	out, err := runner.Output(f.runner, "sudo", "fdesetup", "list")
 	if err != nil {
 		return "", err
 	}
@@ -188,8 +186,47 @@ func (f *FileVault) List() (string, error) {
 	return outText, nil
 }
 
+// fdesetup runs fdesetup with the arguments as root. The credentials of the admin are given
+// as a plist to the standard input with -inputplist, they are never given as arguments.
+//
+// It returns the combined output of the command and an error, if one occurred.
+func (f *FileVault) fdesetup(adminUser string, adminPassword string, args ...string) ([]byte, error) {
+	args = append([]string{"fdesetup"}, args...)
+	args = append(args, "-inputplist")
+
+	cmd := runner.NewCmd("sudo", args...)
+	cmd.Stdin = credentialsPlist(adminUser, adminPassword)
+
+	res, err := f.runner.Exec(cmd)
+
+	return res.Combined, err
+}
+
+// credentialsPlist returns the plist with the username and password used by fdesetup.
+// The values are escaped, any character can be used in the password.
+func credentialsPlist(username string, password string) string {
+	escape := func(value string) string {
+		buf := strings.Builder{}
+		// writing to a strings.Builder never returns an error.
+		xml.EscapeText(&buf, []byte(value))
+
+		return buf.String()
+	}
+
+	return fmt.Sprintf(`<?xml version="1.0" encoding="UTF-8"?>
+<plist version="1.0">
+<dict>
+	<key>Username</key>
+	<string>%s</string>
+	<key>Password</key>
+	<string>%s</string>
+</dict>
+</plist>
+`, escape(username), escape(password))
+}
+
 // parseKey parses the output of the FileVault enabling and returns the key string.
-// If the
+// The key is registered as a secret of the logger, it is never written to the logs.
 func (f *FileVault) parseKey(out string) string {
 	// output is <name> = '<key>'
 	outArr := strings.Split(out, "'")
@@ -200,6 +237,7 @@ func (f *FileVault) parseKey(out string) string {
 
 	// TIL an empty string is added to the array if there is a delimiter at the end!
 	key := outArr[len(outArr)-2]
+	f.log.AddSecrets(key)
 
 	return key
 }
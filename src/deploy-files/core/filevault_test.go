@@ -1,18 +1,22 @@
 package core
 
 import (
+	"encoding/xml"
 	"fmt"
+	"strings"
 	"testing"
 
 	"github.com/bobllor/assert"
 	"github.com/bobllor/macdeploy/src/deploy-files/logger"
+	"github.com/bobllor/macdeploy/src/deploy-files/runner"
+	"github.com/bobllor/macdeploy/src/deploy-files/scripts"
 	"github.com/bobllor/macdeploy/src/deploy-files/yaml"
 )
 
 func TestKeyParse(t *testing.T) {
 	key := "12345-key-here"
 	out := fmt.Sprintf("Output = '%s'", key)
-	f := NewFileVault(yaml.UserInfo{}, nil, logger.NewTestLogger())
+	f := NewFileVault(yaml.UserInfo{}, nil, logger.NewTestLogger(), runner.NewFake())
 
 	t.Run("Normal output", func(t *testing.T) {
 		newKey := f.parseKey(out)
@@ -24,3 +28,91 @@ func TestKeyParse(t *testing.T) {
 		assert.Equal(t, newKey, "")
 	})
 }
+
+func TestFileVaultEnable(t *testing.T) {
+	fake := runner.NewFake()
+	fake.On("fdesetup enable", "Recovery key = '1234-ABCD'\n")
+
+	f := NewFileVault(yaml.UserInfo{}, scripts.NewScript(), logger.NewTestLogger(), fake)
+
+	key := f.Enable("admin", `pa<ss> & 'quote' "$HOME"`)
+	assert.Equal(t, key, "1234-ABCD")
+
+	cmd := fake.Calls()[0]
+	assert.Equal(t, strings.Join(cmd.Args, " "), "fdesetup enable -inputplist")
+	assert.Equal(t, strings.Contains(cmd.Stdin, "<string>pa&lt;ss&gt; &amp; &#39;quote&#39; &#34;$HOME&#34;</string>"), true)
+
+	t.Run("Key Redacted", func(t *testing.T) {
+		fake := runner.NewFake()
+		fake.On("fdesetup enable", "Recovery key = '1234-ABCD'\n")
+
+		log := logger.NewTestLogger()
+		f := NewFileVault(yaml.UserInfo{}, scripts.NewScript(), log, fake)
+
+		key := f.Enable("admin", "password")
+		log.Warnf("The key must be saved: %s", key)
+
+		assert.Equal(t, strings.Contains(log.String(), key), false)
+	})
+
+	t.Run("Failure", func(t *testing.T) {
+		fake := runner.NewFake()
+		fake.Respond(runner.Response{Match: "fdesetup enable", ExitCode: 1})
+
+		f := NewFileVault(yaml.UserInfo{}, scripts.NewScript(), logger.NewTestLogger(), fake)
+		assert.Equal(t, f.Enable("admin", "password"), "")
+	})
+}
+
+func TestFileVaultStatus(t *testing.T) {
+	fake := runner.NewFake()
+	fake.On("fdesetup isactive", "true\n")
+
+	f := NewFileVault(yaml.UserInfo{}, nil, logger.NewTestLogger(), fake)
+
+	status, err := f.Status()
+	assert.Nil(t, err)
+	assert.Equal(t, status, true)
+
+	t.Run("Not Ran", func(t *testing.T) {
+		f := NewFileVault(yaml.UserInfo{}, nil, logger.NewTestLogger(), runner.NewFake())
+
+		_, err := f.Status()
+		assert.NotNil(t, err)
+	})
+}
+
+func TestCredentialsPlist(t *testing.T) {
+	password := `a<b>&c 'd' "$e"`
+	plist := credentialsPlist("John O'Brien", password)
+
+	values := struct {
+		Strings []string `xml:"dict>string"`
+	}{}
+	err := xml.Unmarshal([]byte(plist), &values)
+	assert.Nil(t, err)
+
+	assert.Equal(t, strings.Join(values.Strings, "|"), "John O'Brien|"+password)
+}
+
+func TestAddSecureToken(t *testing.T) {
+	fake := runner.NewFake()
+	admin := yaml.UserInfo{Username: "admin", Password: `admin's $pass`}
+
+	f := NewFileVault(admin, scripts.NewScript(), logger.NewTestLogger(), fake)
+
+	assert.Nil(t, f.AddSecureToken("john.doe", `user "pass" word`))
+
+	cmd := fake.Calls()[len(fake.Calls())-1]
+	assert.Equal(t, fake.Ran("sudo bash -c <secure_token.sh> john.doe admin"), true)
+	assert.Equal(t, cmd.Stdin, "user \"pass\" word\nadmin's $pass\n")
+
+	t.Run("Failed Command", func(t *testing.T) {
+		fake := runner.NewFake()
+		fake.Respond(runner.Response{Match: "secure_token.sh", Stderr: "sudo: a password is required", ExitCode: 1})
+
+		f := NewFileVault(admin, scripts.NewScript(), logger.NewTestLogger(), fake)
+
+		assert.NotNil(t, f.AddSecureToken("john.doe", "password"))
+	})
+}
@@ -3,28 +3,30 @@ package core
 import (
 	"errors"
 	"fmt"
-	"os/exec"
 	"strings"
 
 	"github.com/bobllor/macdeploy/src/deploy-files/logger"
+	"github.com/bobllor/macdeploy/src/deploy-files/runner"
 	"github.com/bobllor/macdeploy/src/deploy-files/scripts"
 )
 
 type Firewall struct {
 	log    *logger.Logger
 	script *scripts.BashScripts
+	runner runner.Runner
 }
 
-func NewFirewall(log *logger.Logger, scripts *scripts.BashScripts) *Firewall {
+func NewFirewall(log *logger.Logger, scripts *scripts.BashScripts, runner runner.Runner) *Firewall {
 	return &Firewall{
 		log:    log,
 		script: scripts,
+		runner: runner,
 	}
 }
 
 // Enable enables the Firewall.
 func (f *Firewall) Enable() error {
-	out, err := exec.Command("sudo", "bash", "-c", f.script.EnableFirewall).CombinedOutput()
+	out, err := runner.CombinedOutput(f.runner, "sudo", "bash", "-c", f.script.EnableFirewall)
 	if err != nil {
 		// FIXME: i dont remember why i use string(out) instead of just error. i added err in a rewrite.
 		return fmt.Errorf("failed to enable Firewall: %s | %v", string(out), err)
@@ -37,8 +39,7 @@ func (f *Firewall) Enable() error {
 
 // Status gets the status of the firewall.
 func (f *Firewall) Status() (bool, error) {
-	cmd := "sudo /usr/libexec/ApplicationFirewall/socketfilterfw --getglobalstate"
-	out, err := exec.Command("bash", "-c", cmd).CombinedOutput()
+	out, err := runner.CombinedOutput(f.runner, "sudo", "/usr/libexec/ApplicationFirewall/socketfilterfw", "--getglobalstate")
 	if err != nil {
 		errMsg := strings.TrimSpace(fmt.Sprintf("Failed to check Firewall status: %s", string(out)))
 		f.log.Warn(errMsg)
@@ -0,0 +1,35 @@
+package core
+
+import (
+	"testing"
+
+	"github.com/bobllor/assert"
+	"github.com/bobllor/macdeploy/src/deploy-files/logger"
+	"github.com/bobllor/macdeploy/src/deploy-files/runner"
+	"github.com/bobllor/macdeploy/src/deploy-files/scripts"
+)
+
+func TestFirewall(t *testing.T) {
+	fake := runner.NewFake()
+	fake.On("--getglobalstate", "Firewall is enabled. (State = 1)\n")
+
+	f := NewFirewall(logger.NewTestLogger(), scripts.NewScript(), fake)
+
+	assert.Nil(t, f.Enable())
+
+	status, err := f.Status()
+	assert.Nil(t, err)
+	assert.Equal(t, status, true)
+
+	assert.Equal(t, fake.Ran("sudo bash -c <enable_firewall.sh>"), true)
+}
+
+func TestFirewallEnableFailure(t *testing.T) {
+	fake := runner.NewFake()
+	fake.Respond(runner.Response{Match: "enable_firewall", Stderr: "not permitted", ExitCode: 1})
+
+	f := NewFirewall(logger.NewTestLogger(), scripts.NewScript(), fake)
+
+	err := f.Enable()
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,128 @@
+package core
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/bobllor/macdeploy/src/deploy-files/runner"
+	"github.com/bobllor/macdeploy/src/deploy-files/utils"
+	"github.com/bobllor/macdeploy/src/deploy-files/yaml"
+)
+
+// Receipt is the receipt of an installed package, read from 'pkgutil --pkg-info'.
+type Receipt struct {
+	ID       string
+	Version  string
+	Volume   string
+	Location string
+}
+
+// GetReceipts returns the identifiers of the installed package receipts from 'pkgutil --pkgs'.
+//
+// An error is returned if the command fails.
+func (f *FileHandler) GetReceipts() ([]string, error) {
+	out, err := runner.Query(f.runner, "pkgutil", "--pkgs")
+	if err != nil {
+		return nil, err
+	}
+
+	receipts := make([]string, 0)
+	for line := range strings.Lines(string(out)) {
+		receipt := strings.TrimSpace(line)
+		if receipt != "" {
+			receipts = append(receipts, receipt)
+		}
+	}
+
+	return receipts, nil
+}
+
+// ReadReceipt returns the receipt of the installed package from 'pkgutil --pkg-info'.
+//
+// An error is returned if the package has no receipt.
+func (f *FileHandler) ReadReceipt(id string) (Receipt, error) {
+	out, err := runner.Query(f.runner, "pkgutil", "--pkg-info", id)
+	if err != nil {
+		return Receipt{}, fmt.Errorf("no receipt found for %s: %v", id, err)
+	}
+
+	receipt := parsePkgInfo(string(out))
+	if receipt.ID == "" {
+		return Receipt{}, fmt.Errorf("no receipt found for %s", id)
+	}
+
+	return receipt, nil
+}
+
+// hasReceipts returns true if every receipt of the package is installed and is not older
+// than the receipt version of the package.
+func (f *FileHandler) hasReceipts(name string, pkg yaml.Package) bool {
+	receipts, err := f.GetReceipts()
+	if err != nil {
+		f.log.Warnf("Failed to get package receipts for %s: %v", name, err)
+		return false
+	}
+
+	for _, id := range pkg.Receipts {
+		found := false
+		for _, receipt := range receipts {
+			if strings.EqualFold(receipt, strings.TrimSpace(id)) {
+				found = true
+				break
+			}
+		}
+
+		if !found {
+			f.log.Infof("No receipt %s found for package %s", id, name)
+			return false
+		}
+
+		if pkg.ReceiptVersion == "" {
+			continue
+		}
+
+		receipt, err := f.ReadReceipt(strings.TrimSpace(id))
+		if err != nil {
+			f.log.Warnf("Failed to read receipt %s of package %s: %v", id, name, err)
+			return false
+		}
+
+		f.log.Debugf("Package: %s | Receipt: %s | Version: %s", name, receipt.ID, receipt.Version)
+
+		if utils.CompareVersions(receipt.Version, pkg.ReceiptVersion) < 0 {
+			f.log.Infof("Found receipt %s version %s, older than the receipt version %s of package %s",
+				receipt.ID, receipt.Version, pkg.ReceiptVersion, name)
+			fmt.Printf("%s is outdated (%s < %s), upgrading\n", name, receipt.Version, pkg.ReceiptVersion)
+
+			return false
+		}
+	}
+
+	return true
+}
+
+// parsePkgInfo parses the output of 'pkgutil --pkg-info', the lines are "<field>: <value>".
+func parsePkgInfo(out string) Receipt {
+	receipt := Receipt{}
+
+	for line := range strings.Lines(out) {
+		field, value, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+
+		value = strings.TrimSpace(value)
+		switch strings.TrimSpace(field) {
+		case "package-id":
+			receipt.ID = value
+		case "version":
+			receipt.Version = value
+		case "volume":
+			receipt.Volume = value
+		case "location":
+			receipt.Location = value
+		}
+	}
+
+	return receipt
+}
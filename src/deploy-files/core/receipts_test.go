@@ -0,0 +1,80 @@
+package core
+
+import (
+	"testing"
+
+	"github.com/bobllor/assert"
+	"github.com/bobllor/macdeploy/src/deploy-files/runner"
+	"github.com/bobllor/macdeploy/src/deploy-files/yaml"
+	"github.com/bobllor/macdeploy/src/tests"
+)
+
+const pkgutilPkgs string = `com.apple.pkg.RosettaUpdateAuto
+com.crowdstrike.falcon.sensor
+com.googlecode.munki.core
+`
+
+const falconPkgInfo string = `package-id: com.crowdstrike.falcon.sensor
+version: 7.10.17706.0
+volume: /
+location: /
+install-time: 1700000000
+`
+
+func TestParsePkgInfo(t *testing.T) {
+	receipt := parsePkgInfo(falconPkgInfo)
+
+	assert.Equal(t, receipt.ID, "com.crowdstrike.falcon.sensor")
+	assert.Equal(t, receipt.Version, "7.10.17706.0")
+	assert.Equal(t, receipt.Volume, "/")
+	assert.Equal(t, receipt.Location, "/")
+}
+
+func TestReadReceipt(t *testing.T) {
+	fake := runner.NewFake()
+	fake.On("pkgutil --pkg-info com.crowdstrike.falcon.sensor", falconPkgInfo)
+	fake.Respond(runner.Response{Match: "pkgutil --pkg-info", Stderr: "No receipt for 'missing' found", ExitCode: 1})
+
+	handler := NewFileHandler(tests.TestLogger, fake)
+
+	receipt, err := handler.ReadReceipt("com.crowdstrike.falcon.sensor")
+	assert.Nil(t, err)
+	assert.Equal(t, receipt.Version, "7.10.17706.0")
+
+	_, err = handler.ReadReceipt("missing")
+	assert.NotNil(t, err)
+}
+
+func TestInstallPackagesReceipts(t *testing.T) {
+	fake := runner.NewFake()
+	fake.On("pkgutil --pkgs", pkgutilPkgs)
+	fake.On("pkgutil --pkg-info com.crowdstrike.falcon.sensor", falconPkgInfo)
+
+	handler := NewFileHandler(tests.TestLogger, fake)
+	handler.AddMapPackages(map[string]yaml.Package{
+		"falcon.pkg": {Receipts: []string{"com.crowdstrike.falcon.sensor"}, ReceiptVersion: "7.11"},
+		"munki.pkg":  {Receipts: []string{"com.googlecode.munki.core"}},
+		"agent.pkg":  {Receipts: []string{"com.googlecode.munki.core", "com.example.agent"}},
+	})
+
+	packages := []string{"/tmp/dist/falcon.pkg", "/tmp/dist/munki.pkg", "/tmp/dist/agent.pkg"}
+	installed := handler.InstallPackages(packages, []string{})
+
+	assert.Equal(t, installed, 3)
+	assert.Equal(t, fake.Ran("installer -pkg /tmp/dist/falcon.pkg"), true)
+	assert.Equal(t, fake.Ran("installer -pkg /tmp/dist/agent.pkg"), true)
+	assert.Equal(t, fake.Ran("installer -pkg /tmp/dist/munki.pkg"), false)
+	assert.Equal(t, len(handler.GetSkippedPackages()), 1)
+
+	t.Run("Dry Run", func(t *testing.T) {
+		fake.SetDryRun(true)
+
+		handler := NewFileHandler(tests.TestLogger, fake)
+		handler.AddMapPackages(map[string]yaml.Package{
+			"munki.pkg": {Receipts: []string{"com.googlecode.munki.core"}},
+		})
+
+		handler.InstallPackages([]string{"/tmp/dist/munki.pkg"}, []string{})
+		assert.Equal(t, len(handler.GetSkippedPackages()), 1)
+	})
+}
@@ -0,0 +1,147 @@
+package core
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/bobllor/macdeploy/src/deploy-files/logger"
+	"github.com/bobllor/macdeploy/src/deploy-files/runner"
+	"github.com/bobllor/macdeploy/src/deploy-files/yaml"
+)
+
+// sudoRefreshInterval is the interval the sudo timestamp is refreshed at. The
+// timestamp expires after 5 minutes by default on macOS.
+const sudoRefreshInterval time.Duration = time.Minute
+
+// SudoSession keeps the sudo timestamp of the admin alive for the lifetime of
+// the deployment, so no stage fails because the timestamp has expired.
+type SudoSession struct {
+	log      *logger.Logger
+	runner   runner.Runner
+	interval time.Duration
+	// refresh refreshes the sudo timestamp.
+	refresh func() error
+
+	mu       sync.Mutex
+	healthy  bool
+	failures int
+	done     chan struct{}
+	stopped  chan struct{}
+}
+
+// NewSudoSession creates a new SudoSession for the admin. The session must be started
+// with Start and stopped with Stop.
+func NewSudoSession(admin yaml.UserInfo, log *logger.Logger, runner runner.Runner) *SudoSession {
+	session := SudoSession{
+		log:      log,
+		runner:   runner,
+		interval: sudoRefreshInterval,
+		refresh: func() error {
+			return admin.InitializeSudo(runner)
+		},
+	}
+
+	return &session
+}
+
+// Start refreshes the sudo timestamp and keeps refreshing it in the background
+// until Stop is called. The background refresh is not used during a dry run.
+//
+// An error is returned if the first refresh fails, the session is still started
+// and will keep trying.
+func (s *SudoSession) Start() error {
+	err := s.Refresh()
+
+	if s.runner.DryRun() {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.done != nil {
+		return err
+	}
+
+	s.done = make(chan struct{})
+	s.stopped = make(chan struct{})
+
+	go s.keepAlive(s.done, s.stopped)
+
+	return err
+}
+
+// Refresh refreshes the sudo timestamp. A failure is logged and the session
+// is marked as unhealthy until a refresh succeeds.
+func (s *SudoSession) Refresh() error {
+	err := s.refresh()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err != nil {
+		s.healthy = false
+		s.failures += 1
+		s.log.Warnf("Failed to refresh sudo (%d in a row): %v", s.failures, err)
+
+		// only the first failure is printed, the rest are logged.
+		if s.failures == 1 {
+			fmt.Println("Failed to refresh sudo, commands that need admin may fail")
+		}
+
+		return err
+	}
+
+	if s.failures > 0 {
+		s.log.Infof("Sudo refresh recovered after %d failures", s.failures)
+	}
+
+	s.healthy = true
+	s.failures = 0
+
+	return nil
+}
+
+// Healthy returns true if the last refresh of the sudo timestamp succeeded.
+func (s *SudoSession) Healthy() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.healthy
+}
+
+// Stop stops the background refresh and waits for it to finish.
+// It can be called more than once.
+func (s *SudoSession) Stop() {
+	s.mu.Lock()
+	done, stopped := s.done, s.stopped
+	s.done = nil
+	s.mu.Unlock()
+
+	if done == nil {
+		return
+	}
+
+	close(done)
+	<-stopped
+
+	s.log.Debug("Stopped sudo session")
+}
+
+// keepAlive refreshes the sudo timestamp at every interval until done is closed.
+func (s *SudoSession) keepAlive(done <-chan struct{}, stopped chan<- struct{}) {
+	defer close(stopped)
+
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-done:
+			return
+		case <-ticker.C:
+			s.Refresh()
+		}
+	}
+}
@@ -0,0 +1,60 @@
+package core
+
+import (
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/bobllor/assert"
+	"github.com/bobllor/macdeploy/src/deploy-files/logger"
+	"github.com/bobllor/macdeploy/src/deploy-files/runner"
+	"github.com/bobllor/macdeploy/src/deploy-files/yaml"
+)
+
+func TestSudoSessionKeepAlive(t *testing.T) {
+	var refreshes atomic.Int32
+
+	s := NewSudoSession(yaml.UserInfo{}, logger.NewTestLogger(), runner.NewFake())
+	s.interval = 10 * time.Millisecond
+	s.refresh = func() error {
+		refreshes.Add(1)
+		return nil
+	}
+
+	assert.Nil(t, s.Start())
+	assert.Equal(t, s.Healthy(), true)
+
+	time.Sleep(50 * time.Millisecond)
+	s.Stop()
+
+	count := refreshes.Load()
+	assert.Equal(t, count > 1, true)
+
+	// no refreshes after the session is stopped.
+	time.Sleep(30 * time.Millisecond)
+	assert.Equal(t, refreshes.Load(), count)
+
+	// stopping again does nothing.
+	s.Stop()
+}
+
+func TestSudoSessionHealth(t *testing.T) {
+	s := NewSudoSession(yaml.UserInfo{}, logger.NewTestLogger(), runner.NewFake())
+
+	var refreshErr error
+	s.refresh = func() error {
+		return refreshErr
+	}
+
+	refreshErr = errors.New("incorrect password")
+	assert.NotNil(t, s.Refresh())
+	assert.NotNil(t, s.Refresh())
+	assert.Equal(t, s.Healthy(), false)
+	assert.Equal(t, s.failures, 2)
+
+	refreshErr = nil
+	assert.Nil(t, s.Refresh())
+	assert.Equal(t, s.Healthy(), true)
+	assert.Equal(t, s.failures, 0)
+}
@@ -5,20 +5,30 @@ import (
 	"errors"
 	"fmt"
 	"os"
-	"os/exec"
 	"strconv"
 	"strings"
 
 	"github.com/bobllor/macdeploy/src/deploy-files/logger"
+	"github.com/bobllor/macdeploy/src/deploy-files/prompt"
+	"github.com/bobllor/macdeploy/src/deploy-files/runner"
 	"github.com/bobllor/macdeploy/src/deploy-files/scripts"
 	"github.com/bobllor/macdeploy/src/deploy-files/utils"
 	"github.com/bobllor/macdeploy/src/deploy-files/yaml"
 )
 
+// dryRunUsername is the username used in place of the name prompt during a dry run.
+const dryRunUsername string = "<username>"
+
+// usersDirectory is the directory of the home folders of the local users.
+const usersDirectory string = "/Users"
+
 type UserMaker struct {
 	adminInfo yaml.UserInfo
 	log       *logger.Logger
 	script    *scripts.BashScripts
+	runner    runner.Runner
+	// usersDir is the directory of the local users, it is usersDirectory by default.
+	usersDir string
 
 	// userCache is a in-memory cache of the users in the users directory.
 	// This will be populated on the existence check. All keys are lowercased.
@@ -26,11 +36,13 @@ type UserMaker struct {
 }
 
 // NewUser creates a new UserMaker to handle user creation.
-func NewUser(adminInfo yaml.UserInfo, scripts *scripts.BashScripts, logger *logger.Logger) *UserMaker {
+func NewUser(adminInfo yaml.UserInfo, scripts *scripts.BashScripts, logger *logger.Logger, runner runner.Runner) *UserMaker {
 	user := UserMaker{
 		adminInfo: adminInfo,
 		log:       logger,
 		script:    scripts,
+		runner:    runner,
+		usersDir:  usersDirectory,
 	}
 
 	return &user
@@ -46,7 +58,17 @@ func (u *UserMaker) CreateAccount(user *yaml.UserInfo, isAdmin bool) (string, er
 	// username will be used for both entries needed.
 	username := user.Username
 
+	// no input is read during a dry run.
+	if username == "" && u.runner.DryRun() {
+		username = dryRunUsername
+	}
+
 	if username == "" {
+		err := prompt.Check("account name")
+		if err != nil {
+			return "", err
+		}
+
 		reader := bufio.NewReader(os.Stdin)
 
 		fmt.Println("\nHit enter to skip the user creation.")
@@ -56,7 +78,8 @@ func (u *UserMaker) CreateAccount(user *yaml.UserInfo, isAdmin bool) (string, er
 
 		fmt.Println("") // for formatting purposes.
 
-		input = input[:len(input)-1]
+		// input is empty if stdin is closed.
+		input = strings.TrimSpace(input)
 
 		if input == "" {
 			u.log.Info("User creation skipped")
@@ -72,13 +95,15 @@ func (u *UserMaker) CreateAccount(user *yaml.UserInfo, isAdmin bool) (string, er
 		u.log.Warnf("No user password was given for %s", username)
 
 		fmt.Println("User password required")
-		err := user.SetPassword(true)
+		err := user.SetPassword(u.runner, true)
 		if err != nil {
 			return "", err
 		}
 
 		u.log.Info("Updated user password, previously was empty")
 	}
+	// the password can also come from the config or the environment.
+	u.log.AddSecrets(user.Password)
 
 	// follows apple's naming convention
 	accountName := utils.FormatUsername(username)
@@ -99,11 +124,13 @@ func (u *UserMaker) CreateAccount(user *yaml.UserInfo, isAdmin bool) (string, er
 		return "", fmt.Errorf("user %s already exists in the system", username)
 	}
 
-	// CreateUserScript takes 3 arguments.
-	out, err := exec.Command("sudo", "bash", "-c",
-		u.script.CreateUser, username, accountName, user.Password, admin).CombinedOutput()
+	// CreateUserScript takes 3 arguments, the password is read from stdin.
+	cmd := runner.NewCmd("sudo", "bash", "-c", u.script.CreateUser, username, accountName, admin)
+	cmd.Stdin = user.Password + "\n"
+
+	res, err := u.runner.Exec(cmd)
 	if err != nil {
-		u.log.Debug(fmt.Sprintf("create user script error: %s", string(out)))
+		u.log.Debug(fmt.Sprintf("create user script error: %s", string(res.Combined)))
 		return "", fmt.Errorf("failed to create user %s: %v", username, err)
 	}
 
@@ -116,15 +143,10 @@ func (u *UserMaker) CreateAccount(user *yaml.UserInfo, isAdmin bool) (string, er
 // DeleteAccount removes the given user from the device. This requires
 // the user to exist.
 func (u *UserMaker) DeleteAccount(username string) error {
-	cmd := fmt.Sprintf(
-		"sudo sysadminctl -deleteUser '%s'",
-		username,
-	)
-
 	// the output is not in stdout, it is not possible to capture.
 	// error codes:
 	//	- user not found (255)
-	_, err := exec.Command("sudo", "bash", "-c", cmd).Output()
+	_, err := runner.Output(u.runner, "sudo", "sysadminctl", "-deleteUser", username)
 	if err != nil {
 		newErr := errors.New("account deletion failed")
 		if strings.Contains(err.Error(), "255") {
@@ -143,7 +165,7 @@ func (u *UserMaker) DeleteAccount(username string) error {
 // GrantAdmin grants the given user admin privileges.
 // The user must exist and is not an admin.
 func (u *UserMaker) GrantAdmin(username string) error {
-	err := u.adminInfo.InitializeSudo()
+	err := u.adminInfo.InitializeSudo(u.runner)
 	if err != nil {
 		return fmt.Errorf("failed to initialize sudo: %v", err)
 	}
@@ -170,7 +192,7 @@ func (u *UserMaker) GrantAdmin(username string) error {
 	fi
 	`
 
-	b, err := exec.Command("sudo", "bash", "-c", cmd, username).CombinedOutput()
+	b, err := runner.CombinedOutput(u.runner, "sudo", "bash", "-c", cmd, username)
 	if err != nil {
 		return fmt.Errorf("failed to grant admin: %v", err)
 	}
@@ -189,7 +211,7 @@ func (u *UserMaker) GrantAdmin(username string) error {
 // RevokeAdmin revokes the admin privileges from the user.
 // The user must exist and is an admin.
 func (u *UserMaker) RevokeAdmin(username string) error {
-	err := u.adminInfo.InitializeSudo()
+	err := u.adminInfo.InitializeSudo(u.runner)
 	if err != nil {
 		return fmt.Errorf("failed to initialize sudo: %v", err)
 	}
@@ -216,7 +238,7 @@ func (u *UserMaker) RevokeAdmin(username string) error {
 	fi
 	`
 
-	b, err := exec.Command("sudo", "bash", "-c", cmd, username).CombinedOutput()
+	b, err := runner.CombinedOutput(u.runner, "sudo", "bash", "-c", cmd, username)
 	if err != nil {
 		return fmt.Errorf("failed to revoke admin (%v)", err)
 	}
@@ -236,9 +258,7 @@ func (u *UserMaker) RevokeAdmin(username string) error {
 //
 // If the password policy fails to run then an error returns.
 func (u *UserMaker) AddPasswordPolicy(username string) error {
-	pwPolicyCmd := fmt.Sprintf("sudo pwpolicy -u '%s' -setpolicy 'newPasswordRequired=1'", username)
-
-	err := exec.Command("bash", "-c", pwPolicyCmd).Run()
+	err := runner.Run(u.runner, "sudo", "pwpolicy", "-u", username, "-setpolicy", "newPasswordRequired=1")
 	if err != nil {
 		return fmt.Errorf("failed to create user policy for %s: %v", username, err)
 	}
@@ -273,7 +293,7 @@ func (u *UserMaker) isAdmin(username string) (bool, error) {
 		return false, fmt.Errorf("user %s does not exist", username)
 	}
 
-	b, err := exec.Command("sudo", "bash", "-c", cmd, strings.ToLower(username)).CombinedOutput()
+	b, err := runner.CombinedOutput(u.runner, "sudo", "bash", "-c", cmd, strings.ToLower(username))
 	if err != nil {
 		return false, err
 	}
@@ -291,7 +311,7 @@ func (u *UserMaker) isAdmin(username string) (bool, error) {
 // List lists the local users on the device. The slice contains the
 // internal usernames, not the display names.
 func (u *UserMaker) List() ([]string, error) {
-	usersPath := "/Users"
+	usersPath := u.usersDir
 
 	// cache is not used due to it potentially being stale
 	dirs, err := os.ReadDir(usersPath)
@@ -321,7 +341,7 @@ func (u *UserMaker) List() ([]string, error) {
 func (u *UserMaker) userExists(username string) (bool, error) {
 	// no dscl . -list /users due to it requiring more parsing and
 	// an additional subprocess exec
-	usersPath := "/Users"
+	usersPath := u.usersDir
 
 	_, ok := u.userCache[username]
 	if ok {
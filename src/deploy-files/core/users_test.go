@@ -3,9 +3,12 @@ package core
 import (
 	"log"
 	"os"
+	"slices"
+	"strings"
 	"testing"
 
 	"github.com/bobllor/macdeploy/src/deploy-files/logger"
+	"github.com/bobllor/macdeploy/src/deploy-files/runner"
 	"github.com/bobllor/macdeploy/src/deploy-files/scripts"
 	"github.com/bobllor/macdeploy/src/deploy-files/utils"
 	"github.com/bobllor/macdeploy/src/deploy-files/yaml"
@@ -54,7 +57,7 @@ func TestFailUserNoPassword(t *testing.T) {
 	user := NewUser(yaml.UserInfo{
 		Username: "admin",
 		Password: "admin",
-	}, scripts.NewScript(), log)
+	}, scripts.NewScript(), log, runner.NewFake())
 
 	_, err := user.CreateAccount(&userInfo, false)
 	if err == nil {
@@ -66,8 +69,61 @@ func TestFailAdminNoPassword(t *testing.T) {
 	config := &yaml.Config{}
 
 	// expects to fail due to the input terminal requirement
-	err := config.Admin.SetPassword(false)
+	err := config.Admin.SetPassword(runner.NewFake(), false)
 	if err == nil {
 		t.Fatal(err)
 	}
 }
+
+func TestDeleteAccount(t *testing.T) {
+	fake := runner.NewFake()
+	user := NewUser(yaml.UserInfo{}, scripts.NewScript(), logger.NewTestLogger(), fake)
+
+	err := user.DeleteAccount("john.doe")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !fake.Ran("sysadminctl -deleteUser") {
+		t.Fatalf("delete command not ran: %v", fake.Commands())
+	}
+
+	fake.Respond(runner.Response{Match: "deleteUser", ExitCode: 255})
+
+	err = user.DeleteAccount("john.doe")
+	if err == nil || err.Error() != "user john.doe does not exist" {
+		t.Fatalf("expected missing user error, got %v", err)
+	}
+}
+
+func TestCreateAccountArguments(t *testing.T) {
+	fake := runner.NewFake()
+	user := NewUser(yaml.UserInfo{}, scripts.NewScript(), logger.NewTestLogger(), fake)
+	user.usersDir = t.TempDir()
+
+	password := `it's a "$HOME" pass`
+	userInfo := yaml.UserInfo{
+		Username: "John O'Brien $USER",
+		Password: password,
+	}
+
+	_, err := user.CreateAccount(&userInfo, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	calls := fake.Calls()
+	if len(calls) != 1 {
+		t.Fatalf("got %d commands, expected 1", len(calls))
+	}
+
+	cmd := calls[0]
+	if cmd.Stdin != password+"\n" {
+		t.Fatalf("got stdin %q, expected the password", cmd.Stdin)
+	}
+	if slices.ContainsFunc(cmd.Args, func(arg string) bool { return strings.Contains(arg, password) }) {
+		t.Fatalf("password found in the arguments: %v", cmd.Args)
+	}
+	if !slices.Contains(cmd.Args, userInfo.Username) {
+		t.Fatalf("username %q not given as one argument: %v", userInfo.Username, cmd.Args)
+	}
+}
@@ -7,6 +7,7 @@ import (
 	"os"
 	"path/filepath"
 	"strings"
+	"sync"
 	"time"
 )
 
@@ -23,6 +24,26 @@ type Buffer interface {
 	String() string
 }
 
+// syncBuffer is a Buffer that can be written and read from multiple goroutines.
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.buf.String()
+}
+
 type Prefix struct {
 	debug    string
 	info     string
@@ -37,6 +58,7 @@ type Logger struct {
 	logLevel  int
 	bufWriter Printer
 	buf       Buffer
+	secrets   *redactor
 }
 
 const (
@@ -82,8 +104,7 @@ func NewLogFile(fileStr string) (*os.File, error) {
 // The logLevel variable is an integer used as a flag for the minimum
 // logging level.
 func NewLogger(printer Printer, logLevel int) *Logger {
-	buf := make([]byte, 0)
-	buffer := bytes.NewBuffer(buf)
+	buffer := &syncBuffer{}
 
 	bufLogger := log.New(buffer, "", log.Ldate|log.Ltime)
 
@@ -99,6 +120,7 @@ func NewLogger(printer Printer, logLevel int) *Logger {
 		logLevel:  logLevel,
 		bufWriter: bufLogger,
 		buf:       buffer,
+		secrets:   &redactor{},
 	}
 
 	return &logger
@@ -107,8 +129,7 @@ func NewLogger(printer Printer, logLevel int) *Logger {
 // NewTestLogger creates a new Logger with preconfigured data
 // for testing.
 func NewTestLogger() *Logger {
-	buf := make([]byte, 0)
-	buffer := bytes.NewBuffer(buf)
+	buffer := &syncBuffer{}
 
 	printer := log.New(os.Stdout, "", log.Ldate|log.Ltime)
 	bufLogger := log.New(buffer, "", log.Ldate|log.Ltime)
@@ -125,6 +146,7 @@ func NewTestLogger() *Logger {
 		logLevel:  Lsilent,
 		bufWriter: bufLogger,
 		buf:       buffer,
+		secrets:   &redactor{},
 	}
 
 	return &logger
@@ -177,7 +199,7 @@ func (l *Logger) SetLogLevel(logLevel int) {
 // Debug sends a message at the DEBUG level.
 func (l *Logger) Debug(v ...any) {
 	vMsg := fmt.Sprint(v...)
-	msg := fmt.Sprintf("%s %s", l.prefix.debug, vMsg)
+	msg := l.Redact(fmt.Sprintf("%s %s", l.prefix.debug, vMsg))
 
 	if l.logLevel <= Ldebug {
 		l.stdout(msg)
@@ -190,7 +212,7 @@ func (l *Logger) Debug(v ...any) {
 // Debugf sends a message at the DEBUG level with formatting.
 func (l *Logger) Debugf(format string, v ...any) {
 	vMsg := fmt.Sprintf(format, v...)
-	msg := fmt.Sprintf("%s %s", l.prefix.debug, vMsg)
+	msg := l.Redact(fmt.Sprintf("%s %s", l.prefix.debug, vMsg))
 
 	if l.logLevel <= Ldebug {
 		l.stdout(msg)
@@ -203,7 +225,7 @@ func (l *Logger) Debugf(format string, v ...any) {
 // Info sends a message at the INFO level.
 func (l *Logger) Info(v ...any) {
 	vMsg := fmt.Sprint(v...)
-	msg := fmt.Sprintf("%s %s", l.prefix.info, vMsg)
+	msg := l.Redact(fmt.Sprintf("%s %s", l.prefix.info, vMsg))
 
 	if l.logLevel <= Linfo {
 		l.stdout(msg)
@@ -216,7 +238,7 @@ func (l *Logger) Info(v ...any) {
 // Infof sends a message at the INFO level with formatting.
 func (l *Logger) Infof(format string, v ...any) {
 	vMsg := fmt.Sprintf(format, v...)
-	msg := fmt.Sprintf("%s %s", l.prefix.info, vMsg)
+	msg := l.Redact(fmt.Sprintf("%s %s", l.prefix.info, vMsg))
 
 	if l.logLevel <= Linfo {
 		l.stdout(msg)
@@ -229,7 +251,7 @@ func (l *Logger) Infof(format string, v ...any) {
 // Warn sends a message at the WARN level.
 func (l *Logger) Warn(v ...any) {
 	vMsg := fmt.Sprint(v...)
-	msg := fmt.Sprintf("%s %s", l.prefix.warn, vMsg)
+	msg := l.Redact(fmt.Sprintf("%s %s", l.prefix.warn, vMsg))
 
 	if l.logLevel <= Lwarn {
 		l.stdout(msg)
@@ -242,7 +264,7 @@ func (l *Logger) Warn(v ...any) {
 // Warnf sends a message at the WARN level with formatting.
 func (l *Logger) Warnf(format string, v ...any) {
 	vMsg := fmt.Sprintf(format, v...)
-	msg := fmt.Sprintf("%s %s", l.prefix.warn, vMsg)
+	msg := l.Redact(fmt.Sprintf("%s %s", l.prefix.warn, vMsg))
 
 	if l.logLevel <= Lwarn {
 		l.stdout(msg)
@@ -255,7 +277,7 @@ func (l *Logger) Warnf(format string, v ...any) {
 // Critical sends a message at the CRITICAL level.
 func (l *Logger) Critical(v ...any) {
 	vMsg := fmt.Sprint(v...)
-	msg := fmt.Sprintf("%s %s", l.prefix.critical, vMsg)
+	msg := l.Redact(fmt.Sprintf("%s %s", l.prefix.critical, vMsg))
 
 	if l.logLevel <= Lcritical {
 		l.stderr(msg)
@@ -268,7 +290,7 @@ func (l *Logger) Critical(v ...any) {
 // Criticalf sends a message at the CRITICAL level with formatting.
 func (l *Logger) Criticalf(format string, v ...any) {
 	vMsg := fmt.Sprintf(format, v...)
-	msg := fmt.Sprintf("%s %s", l.prefix.critical, vMsg)
+	msg := l.Redact(fmt.Sprintf("%s %s", l.prefix.critical, vMsg))
 
 	if l.logLevel <= Lcritical {
 		l.stderr(msg)
@@ -281,7 +303,7 @@ func (l *Logger) Criticalf(format string, v ...any) {
 // Fatal sends a message at the FATAL level.
 func (l *Logger) Fatal(v ...any) {
 	vMsg := fmt.Sprint(v...)
-	msg := fmt.Sprintf("%s %s", l.prefix.fatal, vMsg)
+	msg := l.Redact(fmt.Sprintf("%s %s", l.prefix.fatal, vMsg))
 
 	if l.logLevel <= Lfatal {
 		l.stderr(msg)
@@ -294,7 +316,7 @@ func (l *Logger) Fatal(v ...any) {
 // Fatalf sends a message at the FATAL level with formatting.
 func (l *Logger) Fatalf(format string, v ...any) {
 	vMsg := fmt.Sprintf(format, v...)
-	msg := fmt.Sprintf("%s %s", l.prefix.fatal, vMsg)
+	msg := l.Redact(fmt.Sprintf("%s %s", l.prefix.fatal, vMsg))
 
 	if l.logLevel <= Lfatal {
 		l.stderr(msg)
@@ -304,11 +326,25 @@ func (l *Logger) Fatalf(format string, v ...any) {
 	l.log.Print(msg)
 }
 
+// AddSecrets registers values that are masked in every message of the Logger.
+// This applies to the log file, the terminal, and the buffer sent to the server.
+//
+// Empty values are ignored. A secret only has to be registered once.
+func (l *Logger) AddSecrets(secrets ...string) {
+	l.secrets.add(secrets...)
+}
+
 // String gets the output of the logger as a string.
 func (l *Logger) String() string {
 	return l.buf.String()
 }
 
+// Redact returns the message with the registered secrets masked. It is used to mask
+// the outputs that are not written by the Logger, such as the runner and the report.
+func (l *Logger) Redact(msg string) string {
+	return l.secrets.replace(msg)
+}
+
 // stdout writes any argument to the standard output stream.
 func (l *Logger) stdout(v ...any) {
 	fmt.Fprintln(os.Stdout, v...)
@@ -0,0 +1,48 @@
+package logger
+
+import (
+	"cmp"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// mask is the replacement of a secret in a message.
+const mask string = "********"
+
+// redactor holds the secrets that are masked from the messages.
+type redactor struct {
+	mu      sync.RWMutex
+	secrets []string
+}
+
+// add registers the secrets, the longest secrets are kept first so that
+// a secret containing another secret is masked whole.
+func (r *redactor) add(secrets ...string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, secret := range secrets {
+		if strings.TrimSpace(secret) == "" || slices.Contains(r.secrets, secret) {
+			continue
+		}
+
+		r.secrets = append(r.secrets, secret)
+	}
+
+	slices.SortStableFunc(r.secrets, func(a, b string) int {
+		return cmp.Compare(len(b), len(a))
+	})
+}
+
+// replace returns the message with every registered secret masked.
+func (r *redactor) replace(msg string) string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for _, secret := range r.secrets {
+		msg = strings.ReplaceAll(msg, secret, mask)
+	}
+
+	return msg
+}
@@ -228,3 +228,73 @@ func TestLogToTerminalStderr(t *testing.T) {
 		}
 	}
 }
+
+func TestLogRedactSecrets(t *testing.T) {
+	fileBuf := bytes.NewBuffer([]byte{})
+	log := logger.NewLogger(log.New(fileBuf, "", logFlag), logger.Ldebug)
+
+	secrets := []string{"adminPassw0rd", "userPassw0rd", "ABCD-EFGH-IJKL-MNOP-QRST-UVWX"}
+	log.AddSecrets(secrets...)
+	log.AddSecrets("", "   ", secrets[0])
+
+	old := os.Stdout
+	r, w, _ := os.Pipe()
+	os.Stdout = w
+
+	for _, secret := range secrets {
+		log.Debug("debug ", secret)
+		log.Debugf("debug %s", secret)
+		log.Info("info ", secret)
+		log.Infof("info %s", secret)
+		log.Warn("warn ", secret)
+		log.Warnf("warn %s", secret)
+		log.Critical("critical ", secret)
+		log.Criticalf("critical %s", secret)
+		log.Fatal("fatal ", secret)
+		log.Fatalf("fatal %s", secret)
+	}
+	log.Infof("key %s created with %s", secrets[2], secrets[0])
+
+	out := make(chan string)
+	go func() {
+		var buf bytes.Buffer
+		io.Copy(&buf, r)
+		out <- buf.String()
+	}()
+
+	w.Close()
+	os.Stdout = old
+
+	capturedOut := <-out
+
+	outputs := map[string]string{
+		"buffer": log.String(),
+		"file":   fileBuf.String(),
+		"stdout": capturedOut,
+	}
+
+	for name, content := range outputs {
+		for _, secret := range secrets {
+			if strings.Contains(content, secret) {
+				t.Fatalf("found secret %s in %s output: %s", secret, name, content)
+			}
+		}
+
+		if !strings.Contains(content, "key ******** created with ********") {
+			t.Fatalf("failed to mask secrets in %s output: %s", name, content)
+		}
+	}
+}
+
+func TestLogRedactOverlappingSecrets(t *testing.T) {
+	log := logger.NewLogger(log.New(bytes.NewBuffer([]byte{}), "", logFlag), logger.Lsilent)
+
+	log.AddSecrets("pass", "password123")
+	log.Info("value password123")
+
+	content := log.String()
+
+	if strings.Contains(content, "123") || strings.Contains(content, "pass") {
+		t.Fatalf("failed to mask the longest secret: %s", content)
+	}
+}
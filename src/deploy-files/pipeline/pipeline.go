@@ -0,0 +1,413 @@
+package pipeline
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/bobllor/macdeploy/src/deploy-files/logger"
+)
+
+type Status string
+
+const (
+	StatusCompleted Status = "completed"
+	StatusFailed    Status = "failed"
+	StatusSkipped   Status = "skipped"
+	StatusTimedOut  Status = "timed_out"
+	// StatusAborted is a stage that was stopped by the cancellation of the pipeline.
+	StatusAborted Status = "aborted"
+)
+
+// Stage is a single unit of the deployment process.
+type Stage struct {
+	// Name is the unique name of the stage. It is used for ordering, dependencies
+	// and skipping the stage.
+	Name string
+
+	// DependsOn are the names of the stages that must run before this stage.
+	// Dependencies that are not part of the plan are ignored.
+	DependsOn []string
+
+	// Enabled is a condition checked right before the stage runs. If it returns false,
+	// then the stage is skipped. A nil value means the stage is always enabled.
+	Enabled func() bool
+
+	// Run is the function that performs the stage.
+	Run func() error
+
+	// Timeout is the time limit of the stage, zero is no limit. The context given to the
+	// start hooks is canceled when the limit is reached, the stage must use it to stop.
+	Timeout time.Duration
+}
+
+// Result is the outcome of a stage after the pipeline runs.
+type Result struct {
+	Name     string
+	Status   Status
+	Err      error
+	Duration time.Duration
+}
+
+type Pipeline struct {
+	stages map[string]*Stage
+	// order is the order the stages will run in, before dependencies are resolved.
+	order []string
+	// registered are the stage names in the order they were registered.
+	registered []string
+	skip       map[string]struct{}
+	// hooks are called with the result of each stage.
+	hooks []func(Result)
+	// startHooks are called with the context of each stage before it runs.
+	startHooks []func(context.Context, string)
+	ctx        context.Context
+	log        *logger.Logger
+
+	mu sync.Mutex
+	// current is the running stage, it is empty if no stage is running.
+	current      string
+	currentStart time.Time
+}
+
+// NewPipeline creates a new Pipeline with no stages.
+func NewPipeline(log *logger.Logger) *Pipeline {
+	p := Pipeline{
+		stages:     make(map[string]*Stage),
+		order:      make([]string, 0),
+		registered: make([]string, 0),
+		skip:       make(map[string]struct{}),
+		hooks:      make([]func(Result), 0),
+		startHooks: make([]func(context.Context, string), 0),
+		ctx:        context.Background(),
+		log:        log,
+	}
+
+	return &p
+}
+
+// Register adds a stage to the end of the pipeline.
+//
+// An error is returned if the stage has no name, no run function, or if a
+// stage with the same name is already registered.
+func (p *Pipeline) Register(stage Stage) error {
+	if stage.Name == "" {
+		return errors.New("stage name cannot be empty")
+	}
+	if stage.Run == nil {
+		return fmt.Errorf("stage %s has no run function", stage.Name)
+	}
+	if _, ok := p.stages[stage.Name]; ok {
+		return fmt.Errorf("stage %s is already registered", stage.Name)
+	}
+
+	p.stages[stage.Name] = &stage
+	p.order = append(p.order, stage.Name)
+	p.registered = append(p.registered, stage.Name)
+
+	return nil
+}
+
+// SetOrder replaces the order of the pipeline. Stages that are registered but not
+// included in names will not run.
+//
+// An error is returned if a name is not registered or is duplicated.
+func (p *Pipeline) SetOrder(names []string) error {
+	seen := make(map[string]struct{})
+
+	for _, name := range names {
+		if _, ok := p.stages[name]; !ok {
+			return fmt.Errorf("stage %s does not exist", name)
+		}
+		if _, ok := seen[name]; ok {
+			return fmt.Errorf("stage %s is used more than once", name)
+		}
+
+		seen[name] = struct{}{}
+	}
+
+	p.order = slices.Clone(names)
+
+	return nil
+}
+
+// AddDependencies adds dependencies to a registered stage.
+//
+// An error is returned if the stage is not registered.
+func (p *Pipeline) AddDependencies(name string, dependsOn ...string) error {
+	stage, ok := p.stages[name]
+	if !ok {
+		return fmt.Errorf("stage %s does not exist", name)
+	}
+
+	for _, dep := range dependsOn {
+		if !slices.Contains(stage.DependsOn, dep) {
+			stage.DependsOn = append(stage.DependsOn, dep)
+		}
+	}
+
+	return nil
+}
+
+// Skipped returns true if the stage is marked to be skipped.
+func (p *Pipeline) Skipped(name string) bool {
+	_, ok := p.skip[name]
+
+	return ok
+}
+
+// Skip marks the stages to be skipped when the pipeline runs.
+//
+// An error is returned if a stage is not registered.
+func (p *Pipeline) Skip(names ...string) error {
+	for _, name := range names {
+		if _, ok := p.stages[name]; !ok {
+			return fmt.Errorf("stage %s does not exist", name)
+		}
+
+		p.skip[name] = struct{}{}
+	}
+
+	return nil
+}
+
+// OnResult adds a function that is called with the result of each stage
+// right after the stage finishes or is skipped.
+func (p *Pipeline) OnResult(hook func(Result)) {
+	p.hooks = append(p.hooks, hook)
+}
+
+// OnStart adds a function that is called right before each stage runs, with the
+// context of the stage and its name.
+func (p *Pipeline) OnStart(hook func(ctx context.Context, name string)) {
+	p.startHooks = append(p.startHooks, hook)
+}
+
+// SetContext sets the parent context of the stages. If the context is canceled,
+// the running stage is aborted and the stages after it do not run.
+func (p *Pipeline) SetContext(ctx context.Context) {
+	p.ctx = ctx
+}
+
+// SetTimeout sets the time limit of a registered stage. Zero is no limit.
+//
+// An error is returned if the stage is not registered.
+func (p *Pipeline) SetTimeout(name string, timeout time.Duration) error {
+	stage, ok := p.stages[name]
+	if !ok {
+		return fmt.Errorf("stage %s does not exist", name)
+	}
+
+	stage.Timeout = timeout
+
+	return nil
+}
+
+// Current returns the name of the running stage and how long it has been running.
+// The name is empty if no stage is running.
+func (p *Pipeline) Current() (string, time.Duration) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.current == "" {
+		return "", 0
+	}
+
+	return p.current, time.Since(p.currentStart)
+}
+
+// Stages returns the names of all registered stages in registration order.
+func (p *Pipeline) Stages() []string {
+	return slices.Clone(p.registered)
+}
+
+// Plan returns the stage names in the order they will run.
+//
+// The stages follow the pipeline order, but a stage is always moved after
+// the stages it depends on. An error is returned if a dependency does not exist
+// or if the dependencies have a cycle.
+func (p *Pipeline) Plan() ([]string, error) {
+	inPlan := make(map[string]int, len(p.order))
+	for i, name := range p.order {
+		inPlan[name] = i
+	}
+
+	// number of unresolved dependencies per stage
+	pending := make(map[string]int, len(p.order))
+	// reverse edges, the stages that depend on the key
+	dependents := make(map[string][]string, len(p.order))
+
+	for _, name := range p.order {
+		stage := p.stages[name]
+
+		for _, dep := range stage.DependsOn {
+			if _, ok := p.stages[dep]; !ok {
+				return nil, fmt.Errorf("stage %s depends on unknown stage %s", name, dep)
+			}
+			// dependencies removed from the order have nothing to wait on
+			if _, ok := inPlan[dep]; !ok {
+				continue
+			}
+
+			pending[name] += 1
+			dependents[dep] = append(dependents[dep], name)
+		}
+	}
+
+	ready := make([]string, 0)
+	for _, name := range p.order {
+		if pending[name] == 0 {
+			ready = append(ready, name)
+		}
+	}
+
+	plan := make([]string, 0, len(p.order))
+
+	for len(ready) > 0 {
+		// the earliest stage in the order always goes first
+		slices.SortFunc(ready, func(a, b string) int {
+			return inPlan[a] - inPlan[b]
+		})
+
+		name := ready[0]
+		ready = ready[1:]
+		plan = append(plan, name)
+
+		for _, dependent := range dependents[name] {
+			pending[dependent] -= 1
+			if pending[dependent] == 0 {
+				ready = append(ready, dependent)
+			}
+		}
+	}
+
+	if len(plan) != len(p.order) {
+		cycle := []string{}
+		for _, name := range p.order {
+			if pending[name] > 0 {
+				cycle = append(cycle, name)
+			}
+		}
+
+		return nil, fmt.Errorf("stages have a dependency cycle: %v", cycle)
+	}
+
+	return plan, nil
+}
+
+// Run runs the stages of the pipeline in the planned order.
+// A failed stage is logged and does not stop the stages after it.
+//
+// It returns the results of every planned stage, or an error if the
+// pipeline failed to plan. If the context of the pipeline is canceled, only
+// the results of the stages that have ran are returned.
+func (p *Pipeline) Run() ([]Result, error) {
+	plan, err := p.Plan()
+	if err != nil {
+		return nil, err
+	}
+
+	p.log.Debugf("Stage plan: %v", plan)
+
+	results := make([]Result, 0, len(plan))
+
+	for _, name := range plan {
+		if p.ctx.Err() != nil {
+			p.log.Warnf("Pipeline canceled, stage %s and the stages after it did not run", name)
+			break
+		}
+
+		stage := p.stages[name]
+		result := Result{Name: name}
+
+		if _, ok := p.skip[name]; ok {
+			p.log.Infof("Skipped stage %s", name)
+			result.Status = StatusSkipped
+			results = append(results, p.callHooks(result))
+			continue
+		}
+
+		if stage.Enabled != nil && !stage.Enabled() {
+			p.log.Debugf("Stage %s is not enabled", name)
+			result.Status = StatusSkipped
+			results = append(results, p.callHooks(result))
+			continue
+		}
+
+		p.log.Infof("Starting stage %s", name)
+
+		results = append(results, p.callHooks(p.runStage(stage)))
+	}
+
+	return results, nil
+}
+
+// runStage runs the stage with its time limit and returns its result.
+func (p *Pipeline) runStage(stage *Stage) Result {
+	result := Result{Name: stage.Name}
+
+	ctx, cancel := p.ctx, context.CancelFunc(func() {})
+	if stage.Timeout > 0 {
+		ctx, cancel = context.WithTimeout(p.ctx, stage.Timeout)
+	}
+	defer cancel()
+
+	for _, hook := range p.startHooks {
+		hook(ctx, stage.Name)
+	}
+
+	start := time.Now()
+	p.setCurrent(stage.Name, start)
+
+	// a start hook can cancel the pipeline, the stage does not run.
+	var err error
+	if p.ctx.Err() == nil {
+		err = stage.Run()
+	}
+
+	p.setCurrent("", time.Time{})
+	result.Duration = time.Since(start)
+	result.Status = StatusCompleted
+
+	if errors.Is(p.ctx.Err(), context.Canceled) {
+		p.log.Warnf("Stage %s aborted after %s: %v", stage.Name, result.Duration.Round(time.Second), err)
+		result.Status = StatusAborted
+		result.Err = fmt.Errorf("stage aborted: %w", context.Cause(p.ctx))
+	} else if errors.Is(ctx.Err(), context.DeadlineExceeded) || errors.Is(err, context.DeadlineExceeded) {
+		if err == nil {
+			err = context.DeadlineExceeded
+		}
+
+		p.log.Warnf("Stage %s timed out after %s: %v", stage.Name, result.Duration.Round(time.Second), err)
+		result.Status = StatusTimedOut
+		result.Err = fmt.Errorf("stage timed out: %w", err)
+	} else if err != nil {
+		p.log.Warnf("Stage %s failed: %v", stage.Name, err)
+		result.Status = StatusFailed
+		result.Err = err
+	} else {
+		p.log.Infof("Completed stage %s", stage.Name)
+	}
+
+	return result
+}
+
+// setCurrent sets the running stage.
+func (p *Pipeline) setCurrent(name string, start time.Time) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.current = name
+	p.currentStart = start
+}
+
+// callHooks calls the hooks with the result and returns the result.
+func (p *Pipeline) callHooks(result Result) Result {
+	for _, hook := range p.hooks {
+		hook(result)
+	}
+
+	return result
+}
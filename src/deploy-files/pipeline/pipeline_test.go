@@ -0,0 +1,304 @@
+package pipeline
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/bobllor/assert"
+	"github.com/bobllor/macdeploy/src/deploy-files/logger"
+	"github.com/bobllor/macdeploy/src/tests"
+)
+
+// newTestPipeline returns a Pipeline with the given stage names registered in order.
+// Each stage appends its name to ran when it runs.
+func newTestPipeline(t *testing.T, ran *[]string, names ...string) *Pipeline {
+	p := NewPipeline(logger.NewTestLogger())
+
+	for _, name := range names {
+		err := p.Register(Stage{
+			Name: name,
+			Run: func() error {
+				*ran = append(*ran, name)
+				return nil
+			},
+		})
+		assert.Nil(t, err)
+	}
+
+	return p
+}
+
+func TestRunDefaultOrder(t *testing.T) {
+	ran := []string{}
+	names := []string{"one", "two", "three"}
+	p := newTestPipeline(t, &ran, names...)
+
+	results, err := p.Run()
+	assert.Nil(t, err)
+
+	tests.Checkf(t, !slices.Equal(ran, names), "expected order %v, got %v", names, ran)
+	for _, res := range results {
+		assert.Equal(t, res.Status, StatusCompleted)
+	}
+}
+
+func TestRegisterDuplicate(t *testing.T) {
+	ran := []string{}
+	p := newTestPipeline(t, &ran, "one")
+
+	err := p.Register(Stage{Name: "one", Run: func() error { return nil }})
+	assert.NotNil(t, err)
+
+	err = p.Register(Stage{Name: "no run"})
+	assert.NotNil(t, err)
+}
+
+func TestSetOrder(t *testing.T) {
+	ran := []string{}
+	p := newTestPipeline(t, &ran, "one", "two", "three")
+
+	order := []string{"three", "one"}
+	err := p.SetOrder(order)
+	assert.Nil(t, err)
+
+	_, err = p.Run()
+	assert.Nil(t, err)
+
+	tests.Checkf(t, !slices.Equal(ran, order), "expected order %v, got %v", order, ran)
+
+	t.Run("Unknown Stage", func(t *testing.T) {
+		err := p.SetOrder([]string{"four"})
+		assert.NotNil(t, err)
+	})
+
+	t.Run("Duplicate Stage", func(t *testing.T) {
+		err := p.SetOrder([]string{"one", "one"})
+		assert.NotNil(t, err)
+	})
+}
+
+func TestDependencies(t *testing.T) {
+	ran := []string{}
+	p := newTestPipeline(t, &ran, "one", "two", "three")
+
+	err := p.AddDependencies("one", "three")
+	assert.Nil(t, err)
+
+	plan, err := p.Plan()
+	assert.Nil(t, err)
+
+	expected := []string{"two", "three", "one"}
+	tests.Checkf(t, !slices.Equal(plan, expected), "expected plan %v, got %v", expected, plan)
+
+	t.Run("Removed Dependency", func(t *testing.T) {
+		err := p.SetOrder([]string{"one", "two"})
+		assert.Nil(t, err)
+
+		plan, err := p.Plan()
+		assert.Nil(t, err)
+
+		expected := []string{"one", "two"}
+		tests.Checkf(t, !slices.Equal(plan, expected), "expected plan %v, got %v", expected, plan)
+	})
+}
+
+func TestDependencyErrors(t *testing.T) {
+	t.Run("Cycle", func(t *testing.T) {
+		ran := []string{}
+		p := newTestPipeline(t, &ran, "one", "two", "three")
+
+		assert.Nil(t, p.AddDependencies("one", "three"))
+		assert.Nil(t, p.AddDependencies("three", "one"))
+
+		_, err := p.Plan()
+		assert.NotNil(t, err)
+
+		_, err = p.Run()
+		assert.NotNil(t, err)
+		assert.Equal(t, len(ran), 0)
+	})
+
+	t.Run("Unknown Dependency", func(t *testing.T) {
+		ran := []string{}
+		p := newTestPipeline(t, &ran, "one")
+
+		assert.Nil(t, p.AddDependencies("one", "missing"))
+
+		_, err := p.Plan()
+		assert.NotNil(t, err)
+	})
+}
+
+func TestSkipAndEnabled(t *testing.T) {
+	ran := []string{}
+	p := newTestPipeline(t, &ran, "one", "two")
+
+	err := p.Register(Stage{
+		Name:    "disabled",
+		Enabled: func() bool { return false },
+		Run: func() error {
+			ran = append(ran, "disabled")
+			return nil
+		},
+	})
+	assert.Nil(t, err)
+
+	assert.Nil(t, p.Skip("two"))
+	assert.NotNil(t, p.Skip("missing"))
+
+	results, err := p.Run()
+	assert.Nil(t, err)
+
+	tests.Checkf(t, !slices.Equal(ran, []string{"one"}), "expected only 'one' to run, got %v", ran)
+	assert.Equal(t, results[1].Status, StatusSkipped)
+	assert.Equal(t, results[2].Status, StatusSkipped)
+}
+
+func TestFailedStageContinues(t *testing.T) {
+	ran := []string{}
+	p := NewPipeline(logger.NewTestLogger())
+
+	assert.Nil(t, p.Register(Stage{
+		Name: "fail",
+		Run:  func() error { return errors.New("failed stage") },
+	}))
+	assert.Nil(t, p.Register(Stage{
+		Name: "after",
+		Run: func() error {
+			ran = append(ran, "after")
+			return nil
+		},
+	}))
+
+	results, err := p.Run()
+	assert.Nil(t, err)
+
+	assert.Equal(t, results[0].Status, StatusFailed)
+	assert.NotNil(t, results[0].Err)
+	assert.Equal(t, results[1].Status, StatusCompleted)
+	assert.Equal(t, len(ran), 1)
+}
+
+func TestOnResult(t *testing.T) {
+	ran := []string{}
+	p := newTestPipeline(t, &ran, "one", "two")
+
+	assert.Nil(t, p.Skip("two"))
+
+	hookResults := []Result{}
+	p.OnResult(func(res Result) {
+		hookResults = append(hookResults, res)
+	})
+
+	results, err := p.Run()
+	assert.Nil(t, err)
+
+	assert.Equal(t, len(hookResults), len(results))
+	assert.Equal(t, hookResults[0].Status, StatusCompleted)
+	assert.Equal(t, hookResults[1].Status, StatusSkipped)
+}
+
+func TestStageTimeout(t *testing.T) {
+	p := NewPipeline(logger.NewTestLogger())
+
+	var stageCtx context.Context
+	p.OnStart(func(ctx context.Context, name string) {
+		stageCtx = ctx
+	})
+
+	err := p.Register(Stage{
+		Name:    "slow",
+		Timeout: 50 * time.Millisecond,
+		Run: func() error {
+			<-stageCtx.Done()
+			return stageCtx.Err()
+		},
+	})
+	assert.Nil(t, err)
+
+	err = p.Register(Stage{
+		Name: "fast",
+		Run: func() error {
+			return nil
+		},
+	})
+	assert.Nil(t, err)
+
+	results, err := p.Run()
+	assert.Nil(t, err)
+
+	assert.Equal(t, results[0].Status, StatusTimedOut)
+	assert.Equal(t, errors.Is(results[0].Err, context.DeadlineExceeded), true)
+	assert.Equal(t, results[1].Status, StatusCompleted)
+
+	name, _ := p.Current()
+	assert.Equal(t, name, "")
+}
+
+func TestCancel(t *testing.T) {
+	p := NewPipeline(logger.NewTestLogger())
+
+	ctx, cancel := context.WithCancelCause(context.Background())
+	p.SetContext(ctx)
+
+	var stageCtx context.Context
+	p.OnStart(func(ctx context.Context, name string) {
+		stageCtx = ctx
+	})
+
+	err := p.Register(Stage{
+		Name: "interrupted",
+		Run: func() error {
+			cancel(errors.New("interrupt"))
+
+			<-stageCtx.Done()
+			return stageCtx.Err()
+		},
+	})
+	assert.Nil(t, err)
+
+	err = p.Register(Stage{
+		Name: "after",
+		Run: func() error {
+			return nil
+		},
+	})
+	assert.Nil(t, err)
+
+	results, err := p.Run()
+	assert.Nil(t, err)
+
+	assert.Equal(t, len(results), 1)
+	assert.Equal(t, results[0].Status, StatusAborted)
+	assert.Equal(t, results[0].Err.Error(), "stage aborted: interrupt")
+}
+
+func TestCancelOnStart(t *testing.T) {
+	p := NewPipeline(logger.NewTestLogger())
+
+	ctx, cancel := context.WithCancelCause(context.Background())
+	p.SetContext(ctx)
+
+	p.OnStart(func(ctx context.Context, name string) {
+		cancel(errors.New("hook failed"))
+	})
+
+	ran := false
+	err := p.Register(Stage{
+		Name: "canceled",
+		Run: func() error {
+			ran = true
+			return nil
+		},
+	})
+	assert.Nil(t, err)
+
+	results, err := p.Run()
+	assert.Nil(t, err)
+
+	assert.Equal(t, ran, false)
+	assert.Equal(t, results[0].Status, StatusAborted)
+}
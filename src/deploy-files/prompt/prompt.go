@@ -0,0 +1,93 @@
+package prompt
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// ErrNonInteractive is returned when an input is required while non-interactive mode is enabled.
+var ErrNonInteractive = errors.New("input required in non-interactive mode")
+
+// interactive is false if the prompts are disabled.
+var interactive bool = true
+
+// SetInteractive enables or disables the prompts for input.
+func SetInteractive(isInteractive bool) {
+	interactive = isInteractive
+}
+
+// Interactive returns true if prompts for input are allowed.
+func Interactive() bool {
+	return interactive
+}
+
+// Check returns an error if prompts are not allowed. The value is the name of the
+// input, and the sources are the ways to give the input instead of a prompt.
+func Check(value string, sources ...string) error {
+	if interactive {
+		return nil
+	}
+
+	if len(sources) == 0 {
+		return fmt.Errorf("%w: %s", ErrNonInteractive, value)
+	}
+
+	return fmt.Errorf("%w: %s, set it with %s", ErrNonInteractive, value, strings.Join(sources, " or "))
+}
+
+// Select prompts a numbered list of the options and returns the chosen option.
+// The option can be chosen by its number or its name. An empty input chooses
+// no option, which returns an empty string.
+//
+// An error is returned if prompts are not allowed.
+func Select(label string, options []string) (string, error) {
+	err := Check(label)
+	if err != nil {
+		return "", err
+	}
+
+	return selectOption(os.Stdin, label, options), nil
+}
+
+// selectOption reads the chosen option from the reader. It will re-prompt
+// until a valid option or an empty input is given.
+func selectOption(r io.Reader, label string, options []string) string {
+	reader := bufio.NewReader(r)
+
+	fmt.Printf("\nSelect the %s:\n", label)
+	for i, option := range options {
+		fmt.Printf("  %d) %s\n", i+1, option)
+	}
+
+	for {
+		fmt.Print("Enter a number or name (hit enter to skip): ")
+		input, err := reader.ReadString('\n')
+		input = strings.TrimSpace(input)
+
+		if input == "" {
+			return ""
+		}
+
+		if slices.Contains(options, input) {
+			return input
+		}
+
+		i, convErr := strconv.Atoi(input)
+		if convErr == nil && i > 0 && i <= len(options) {
+			return options[i-1]
+		}
+
+		// stdin is closed, no valid input can be given.
+		if err != nil {
+			return ""
+		}
+
+		fmt.Printf("Invalid response [%s]\n", input)
+	}
+}
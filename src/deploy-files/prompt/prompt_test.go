@@ -0,0 +1,32 @@
+package prompt
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/bobllor/assert"
+)
+
+func TestCheck(t *testing.T) {
+	assert.Nil(t, Check("admin password"))
+
+	SetInteractive(false)
+	defer SetInteractive(true)
+
+	err := Check("admin password", "admin.password in the config", "MACDEPLOY_ADMIN_PASSWORD")
+	assert.NotNil(t, err)
+	assert.Equal(t, errors.Is(err, ErrNonInteractive), true)
+	assert.Equal(t, err.Error(), "input required in non-interactive mode: admin password, set it with admin.password in the config or MACDEPLOY_ADMIN_PASSWORD")
+}
+
+func TestSelectOption(t *testing.T) {
+	options := []string{"engineering", "loaner", "sales"}
+
+	assert.Equal(t, selectOption(strings.NewReader("2\n"), "profile", options), "loaner")
+	assert.Equal(t, selectOption(strings.NewReader("sales\n"), "profile", options), "sales")
+	assert.Equal(t, selectOption(strings.NewReader("\n"), "profile", options), "")
+	assert.Equal(t, selectOption(strings.NewReader("9\nengineering\n"), "profile", options), "engineering")
+	// closed stdin
+	assert.Equal(t, selectOption(strings.NewReader("9"), "profile", options), "")
+}
@@ -0,0 +1,215 @@
+package report
+
+import (
+	"encoding/json"
+	"os"
+	"slices"
+	"time"
+
+	"github.com/bobllor/macdeploy/src/deploy-files/checksum"
+	"github.com/bobllor/macdeploy/src/deploy-files/pipeline"
+	"github.com/bobllor/macdeploy/src/deploy-files/runner"
+)
+
+// StatusPending is the status of a planned stage that has not ran yet.
+const StatusPending pipeline.Status = "pending"
+
+// Report is the machine-readable summary of a deployment.
+type Report struct {
+	SerialTag       string          `json:"serial_tag"`
+	RunID           string          `json:"run_id"`
+	Profile         string          `json:"profile,omitempty"`
+	Started         time.Time       `json:"started"`
+	Finished        time.Time       `json:"finished"`
+	DurationSeconds float64         `json:"duration_seconds"`
+	DryRun          bool            `json:"dry_run"`
+	ExitCode        int             `json:"exit_code"`
+	Stages          []StageReport   `json:"stages"`
+	Packages        PackageReport   `json:"packages"`
+	UsersCreated    []string        `json:"users_created"`
+	FileVault       FileVaultReport `json:"filevault"`
+	Firewall        FirewallReport  `json:"firewall"`
+	Scripts         []ScriptReport  `json:"scripts"`
+	// ChecksumFailures are the files that were refused due to a failed checksum verification.
+	ChecksumFailures []ChecksumReport `json:"checksum_failures"`
+	// TimedOut are the commands that reached their time limit.
+	TimedOut []string `json:"timed_out"`
+	// Aborted indicates that the deployment was interrupted before it finished.
+	Aborted bool `json:"aborted"`
+
+	// redactor masks the secrets of the outputs and the errors, the report is sent to the server.
+	redactor runner.Redactor
+}
+
+type StageReport struct {
+	Name            string          `json:"name"`
+	Status          pipeline.Status `json:"status"`
+	DurationSeconds float64         `json:"duration_seconds"`
+	Error           string          `json:"error,omitempty"`
+}
+
+type PackageReport struct {
+	Installed []string `json:"installed"`
+	// Skipped are the packages with an existing installation.
+	Skipped []string `json:"skipped"`
+	// SkippedByCondition are the packages with conditions not met by the device, k:v <package>:<reason>.
+	SkippedByCondition map[string]string `json:"skipped_by_condition"`
+	Failed             []string          `json:"failed"`
+}
+
+type FileVaultReport struct {
+	Enabled     bool `json:"enabled"`
+	KeyEscrowed bool `json:"key_escrowed"`
+}
+
+type FirewallReport struct {
+	Enabled bool `json:"enabled"`
+}
+
+type ScriptReport struct {
+	Name string `json:"name"`
+	// ExitCode is the exit code of the script, -1 is used if the script could not run.
+	ExitCode int    `json:"exit_code"`
+	Error    string `json:"error,omitempty"`
+	// Stdout and Stderr are the outputs of the script, only the end is kept
+	// if an output is larger than maxScriptOutput.
+	Stdout string `json:"stdout,omitempty"`
+	Stderr string `json:"stderr,omitempty"`
+}
+
+type ChecksumReport struct {
+	File     string `json:"file"`
+	Expected string `json:"expected"`
+	// Actual is the checksum of the file, it is empty if the file could not be read.
+	Actual string `json:"actual,omitempty"`
+	Error  string `json:"error"`
+}
+
+// maxScriptOutput is the max size in bytes of an output of a script in the report.
+const maxScriptOutput int = 64 * 1024
+
+// NewReport creates a new empty Report for the device.
+func NewReport(serialTag string, dryRun bool) *Report {
+	report := Report{
+		SerialTag: serialTag,
+		Started:   time.Now(),
+		DryRun:    dryRun,
+		Stages:    make([]StageReport, 0),
+		Packages: PackageReport{
+			Installed:          make([]string, 0),
+			Skipped:            make([]string, 0),
+			SkippedByCondition: make(map[string]string),
+			Failed:             make([]string, 0),
+		},
+		UsersCreated:     make([]string, 0),
+		Scripts:          make([]ScriptReport, 0),
+		ChecksumFailures: make([]ChecksumReport, 0),
+		TimedOut:         make([]string, 0),
+	}
+
+	return &report
+}
+
+// SetRedactor sets the Redactor that masks the secrets of the script outputs and the errors
+// before they are added to the report.
+func (r *Report) SetRedactor(redactor runner.Redactor) {
+	r.redactor = redactor
+}
+
+// redact returns the message with the secrets masked, the message is unchanged if no
+// Redactor is set.
+func (r *Report) redact(msg string) string {
+	if r.redactor == nil {
+		return msg
+	}
+
+	return r.redactor.Redact(msg)
+}
+
+// AddStage adds the result of a stage.
+func (r *Report) AddStage(result pipeline.Result) {
+	stage := StageReport{
+		Name:            result.Name,
+		Status:          result.Status,
+		DurationSeconds: result.Duration.Seconds(),
+	}
+	if result.Err != nil {
+		stage.Error = r.redact(result.Err.Error())
+	}
+
+	r.Stages = append(r.Stages, stage)
+}
+
+// AddScript adds the outcome and the outputs of a script. The exit code is taken from the error.
+func (r *Report) AddScript(name string, stdout string, stderr string, err error) {
+	script := ScriptReport{
+		Name:     name,
+		ExitCode: runner.ExitCode(err),
+		Stdout:   truncateOutput(r.redact(stdout)),
+		Stderr:   truncateOutput(r.redact(stderr)),
+	}
+
+	if err != nil {
+		script.Error = r.redact(err.Error())
+	}
+
+	r.Scripts = append(r.Scripts, script)
+}
+
+// SetChecksumFailures sets the files that were refused due to a failed checksum verification.
+func (r *Report) SetChecksumFailures(errs []*checksum.VerifyError) {
+	r.ChecksumFailures = make([]ChecksumReport, 0, len(errs))
+
+	for _, err := range errs {
+		r.ChecksumFailures = append(r.ChecksumFailures, ChecksumReport{
+			File:     err.Path,
+			Expected: err.Expected,
+			Actual:   err.Actual,
+			Error:    err.Error(),
+		})
+	}
+}
+
+// truncateOutput returns the end of the output if it is larger than maxScriptOutput.
+func truncateOutput(out string) string {
+	if len(out) <= maxScriptOutput {
+		return out
+	}
+
+	return "[truncated]\n" + out[len(out)-maxScriptOutput:]
+}
+
+// Finish sets the finish time and duration of the Report.
+func (r *Report) Finish() {
+	r.Finished = time.Now()
+	r.DurationSeconds = r.Finished.Sub(r.Started).Seconds()
+}
+
+// WithPending returns a copy of the Report with the stages of the plan that
+// have not ran added as pending.
+func (r *Report) WithPending(plan []string) *Report {
+	report := *r
+	report.Stages = slices.Clone(r.Stages)
+
+	for _, name := range plan {
+		hasStage := slices.ContainsFunc(report.Stages, func(s StageReport) bool {
+			return s.Name == name
+		})
+
+		if !hasStage {
+			report.Stages = append(report.Stages, StageReport{Name: name, Status: StatusPending})
+		}
+	}
+
+	return &report
+}
+
+// Save writes the Report as JSON to the path.
+func (r *Report) Save(path string) error {
+	data, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(path, data, 0o600)
+}
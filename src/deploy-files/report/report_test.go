@@ -0,0 +1,117 @@
+package report
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/bobllor/assert"
+	"github.com/bobllor/macdeploy/src/deploy-files/checksum"
+	"github.com/bobllor/macdeploy/src/deploy-files/logger"
+	"github.com/bobllor/macdeploy/src/deploy-files/pipeline"
+	"github.com/bobllor/macdeploy/src/deploy-files/runner"
+)
+
+func TestAddScript(t *testing.T) {
+	report := NewReport("SERIAL", false)
+
+	exitErr := exec.Command("bash", "-c", "exit 3").Run()
+
+	report.AddScript("success.sh", "done", "", nil)
+	report.AddScript("exit.sh", "", "failed", exitErr)
+	report.AddScript("missing.sh", "", "", errors.New("script not found"))
+
+	assert.Equal(t, report.Scripts[0].ExitCode, 0)
+	assert.Equal(t, report.Scripts[0].Stdout, "done")
+	assert.Equal(t, report.Scripts[1].Stderr, "failed")
+	assert.Equal(t, report.Scripts[1].ExitCode, 3)
+	assert.Equal(t, report.Scripts[2].ExitCode, -1)
+	assert.Equal(t, report.Scripts[2].Error, "script not found")
+}
+
+func TestAddScriptRedact(t *testing.T) {
+	key := "ABCD-EFGH-IJKL-MNOP-QRST-UVWX"
+	log := logger.NewTestLogger()
+	log.AddSecrets(key)
+
+	report := NewReport("SERIAL", false)
+	report.SetRedactor(log)
+
+	res, err := runner.New().Exec(runner.NewCmd("bash", "-c", "echo \"key: $1\"; echo \"$1\" >&2; exit 1", "bash", key))
+	report.AddScript("leak.sh", string(res.Stdout), string(res.Stderr), fmt.Errorf("script failed: %s: %w", key, err))
+
+	path := filepath.Join(t.TempDir(), "report.json")
+	assert.Nil(t, report.Save(path))
+
+	data, err := os.ReadFile(path)
+	assert.Nil(t, err)
+	assert.Equal(t, strings.Contains(string(data), key), false)
+	assert.Equal(t, report.Scripts[0].Stdout, "key: ********\n")
+	assert.Equal(t, report.Scripts[0].ExitCode, 1)
+}
+
+func TestAddScriptTruncate(t *testing.T) {
+	report := NewReport("SERIAL", false)
+
+	out := strings.Repeat("a", maxScriptOutput) + "end"
+	report.AddScript("long.sh", out, "", nil)
+
+	stdout := report.Scripts[0].Stdout
+	assert.Equal(t, strings.HasPrefix(stdout, "[truncated]\n"), true)
+	assert.Equal(t, strings.HasSuffix(stdout, "end"), true)
+	assert.Equal(t, len(stdout), len("[truncated]\n")+maxScriptOutput)
+}
+
+func TestWithPending(t *testing.T) {
+	report := NewReport("SERIAL", false)
+	report.AddStage(pipeline.Result{Name: "one", Status: pipeline.StatusCompleted})
+	report.AddStage(pipeline.Result{Name: "two", Status: pipeline.StatusFailed, Err: errors.New("failed")})
+
+	pending := report.WithPending([]string{"one", "two", "three"})
+
+	assert.Equal(t, len(pending.Stages), 3)
+	assert.Equal(t, pending.Stages[1].Error, "failed")
+	assert.Equal(t, pending.Stages[2].Status, StatusPending)
+	// the original report is not changed
+	assert.Equal(t, len(report.Stages), 2)
+}
+
+func TestSave(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "report.json")
+
+	report := NewReport("SERIAL", true)
+	report.Packages.Installed = append(report.Packages.Installed, "package.pkg")
+	report.Finish()
+
+	assert.Nil(t, report.Save(path))
+
+	data, err := os.ReadFile(path)
+	assert.Nil(t, err)
+
+	saved := map[string]any{}
+	assert.Nil(t, json.Unmarshal(data, &saved))
+
+	assert.Equal(t, saved["serial_tag"], "SERIAL")
+	assert.Equal(t, saved["dry_run"], true)
+	assert.NotNil(t, saved["packages"])
+}
+
+func TestSetChecksumFailures(t *testing.T) {
+	report := NewReport("SERIAL", false)
+
+	report.SetChecksumFailures([]*checksum.VerifyError{
+		{Path: "dist/app.pkg", Expected: "aaaa", Actual: "bbbb"},
+		{Path: "dist/setup.sh", Expected: "cccc", Err: os.ErrNotExist},
+	})
+
+	assert.Equal(t, len(report.ChecksumFailures), 2)
+	assert.Equal(t, report.ChecksumFailures[0].File, "dist/app.pkg")
+	assert.Equal(t, report.ChecksumFailures[0].Actual, "bbbb")
+	assert.Equal(t, strings.Contains(report.ChecksumFailures[0].Error, "checksum mismatch"), true)
+	assert.Equal(t, report.ChecksumFailures[1].Actual, "")
+}
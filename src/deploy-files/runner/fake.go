@@ -0,0 +1,202 @@
+package runner
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// Fake is a Runner that records the commands and returns scripted results instead
+// of executing them. It is used to test the components on any system.
+type Fake struct {
+	dryRun bool
+
+	mu        sync.Mutex
+	responses []*Response
+	calls     []Cmd
+	plans     []string
+}
+
+// Response is a scripted result of the commands that match it.
+type Response struct {
+	// Match is a part of the printed command that selects the response, see Format.
+	// An empty Match selects every command.
+	Match string
+
+	Stdout string
+	Stderr string
+
+	// ExitCode is the exit status of the command, a non-zero code returns an error.
+	ExitCode int
+
+	// Err is returned instead of the exit status error, if set.
+	Err error
+
+	// Times is the number of commands the response is used for, zero is unlimited.
+	Times int
+}
+
+// exitError is the error of a fake command with a non-zero exit status.
+type exitError struct {
+	code int
+}
+
+func (e *exitError) Error() string {
+	return fmt.Sprintf("exit status %d", e.code)
+}
+
+// ExitCode returns the exit status of the command.
+func (e *exitError) ExitCode() int {
+	return e.code
+}
+
+// NewFake creates a new Fake, the commands succeed with empty outputs
+// unless a response is added.
+func NewFake() *Fake {
+	fake := Fake{
+		dryRun:    false,
+		responses: make([]*Response, 0),
+		calls:     make([]Cmd, 0),
+		plans:     make([]string, 0),
+	}
+
+	return &fake
+}
+
+// SetDryRun enables or disables the dry run. The commands are still recorded
+// during a dry run, but the responses are only used for queries.
+func (f *Fake) SetDryRun(dryRun bool) {
+	f.dryRun = dryRun
+}
+
+// DryRun returns true if dry run is enabled.
+func (f *Fake) DryRun() bool {
+	return f.dryRun
+}
+
+// Respond adds a response to the commands that match it. The responses are
+// checked in the order they were added.
+func (f *Fake) Respond(res Response) *Fake {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.responses = append(f.responses, &res)
+
+	return f
+}
+
+// On adds a response with the standard output to the commands that match it.
+func (f *Fake) On(match string, stdout string) *Fake {
+	return f.Respond(Response{Match: match, Stdout: stdout})
+}
+
+// Exec records the command and returns the first response that matches it.
+// The OnLine function of the command is called with the lines of the response.
+func (f *Fake) Exec(cmd Cmd) (Result, error) {
+	f.mu.Lock()
+	f.calls = append(f.calls, cmd)
+
+	if f.dryRun && !cmd.Query {
+		f.mu.Unlock()
+		return Result{}, nil
+	}
+
+	command := Format(cmd.Name, cmd.Args...)
+	res := &Response{}
+
+	for i, response := range f.responses {
+		if !strings.Contains(command, response.Match) {
+			continue
+		}
+
+		res = response
+		if res.Times > 0 {
+			res.Times -= 1
+			if res.Times == 0 {
+				f.responses = slices.Delete(f.responses, i, i+1)
+			}
+		}
+
+		break
+	}
+	f.mu.Unlock()
+
+	if cmd.OnLine != nil {
+		for _, line := range outputLines(res.Stdout) {
+			cmd.OnLine(line, false)
+		}
+		for _, line := range outputLines(res.Stderr) {
+			cmd.OnLine(line, true)
+		}
+	}
+
+	result := Result{
+		Stdout:   []byte(res.Stdout),
+		Stderr:   []byte(res.Stderr),
+		Combined: []byte(res.Stdout + res.Stderr),
+	}
+
+	if res.Err != nil {
+		return result, res.Err
+	}
+	if res.ExitCode != 0 {
+		return result, &exitError{code: res.ExitCode}
+	}
+
+	return result, nil
+}
+
+// Planf records the action during a dry run.
+func (f *Fake) Planf(format string, v ...any) {
+	if !f.dryRun {
+		return
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.plans = append(f.plans, fmt.Sprintf(format, v...))
+}
+
+// Calls returns the recorded commands in the order they were ran.
+func (f *Fake) Calls() []Cmd {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return slices.Clone(f.calls)
+}
+
+// Commands returns the printable form of the recorded commands, see Format.
+func (f *Fake) Commands() []string {
+	commands := []string{}
+	for _, cmd := range f.Calls() {
+		commands = append(commands, Format(cmd.Name, cmd.Args...))
+	}
+
+	return commands
+}
+
+// Ran returns true if a recorded command contains the match.
+func (f *Fake) Ran(match string) bool {
+	return slices.ContainsFunc(f.Commands(), func(command string) bool {
+		return strings.Contains(command, match)
+	})
+}
+
+// Plans returns the recorded actions of the dry run.
+func (f *Fake) Plans() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return slices.Clone(f.plans)
+}
+
+// outputLines returns the lines of the output without the last line ending.
+func outputLines(out string) []string {
+	if out == "" {
+		return []string{}
+	}
+
+	return strings.Split(strings.TrimSuffix(out, "\n"), "\n")
+}
@@ -0,0 +1,65 @@
+package runner
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/bobllor/assert"
+)
+
+func TestFake(t *testing.T) {
+	fake := NewFake()
+	fake.On("whoami", "admin\n")
+	fake.Respond(Response{Match: "fdesetup status", ExitCode: 1, Stderr: "failed\n", Times: 1})
+
+	out, err := Output(fake, "whoami")
+	assert.Nil(t, err)
+	assert.Equal(t, string(out), "admin\n")
+
+	lines := []string{}
+	cmd := NewCmd("fdesetup", "status")
+	cmd.OnLine = func(line string, stderr bool) {
+		lines = append(lines, line)
+	}
+
+	res, err := fake.Exec(cmd)
+	assert.Equal(t, ExitCode(err), 1)
+	assert.Equal(t, string(res.Stderr), "failed\n")
+	assert.Equal(t, strings.Join(lines, ","), "failed")
+
+	// the response is only used once.
+	assert.Nil(t, Run(fake, "fdesetup", "status"))
+
+	assert.Equal(t, strings.Join(fake.Commands(), ","), "whoami,fdesetup status,fdesetup status")
+	assert.Equal(t, fake.Ran("fdesetup"), true)
+	assert.Equal(t, fake.Ran("sysadminctl"), false)
+}
+
+func TestFakeError(t *testing.T) {
+	fake := NewFake()
+	fake.Respond(Response{Match: "sleep", Err: ErrTimeout})
+
+	err := Run(fake, "sleep", "5")
+	assert.Equal(t, errors.Is(err, ErrTimeout), true)
+	assert.Equal(t, ExitCode(err), -1)
+}
+
+func TestFakeDryRun(t *testing.T) {
+	fake := NewFake()
+	fake.SetDryRun(true)
+	fake.On("", "output")
+
+	out, err := Output(fake, "touch", "file")
+	assert.Nil(t, err)
+	assert.Equal(t, len(out), 0)
+
+	out, err = Query(fake, "whoami")
+	assert.Nil(t, err)
+	assert.Equal(t, string(out), "output")
+
+	fake.Planf("copy %s", "file")
+
+	assert.Equal(t, fake.Ran("touch file"), true)
+	assert.Equal(t, strings.Join(fake.Plans(), ","), "copy file")
+}
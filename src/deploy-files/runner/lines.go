@@ -0,0 +1,81 @@
+package runner
+
+import (
+	"bytes"
+	"strings"
+	"sync"
+)
+
+// lineWriter calls a function with each line written to it, without the line ending.
+type lineWriter struct {
+	buf    []byte
+	onLine func(line string)
+}
+
+// newLineWriter creates a new lineWriter that calls onLine with each line.
+func newLineWriter(onLine func(line string)) *lineWriter {
+	w := lineWriter{
+		buf:    make([]byte, 0),
+		onLine: onLine,
+	}
+
+	return &w
+}
+
+// Write buffers the bytes and calls the function for every complete line.
+func (w *lineWriter) Write(p []byte) (int, error) {
+	w.buf = append(w.buf, p...)
+
+	for {
+		i := bytes.IndexByte(w.buf, '\n')
+		if i < 0 {
+			break
+		}
+
+		w.onLine(strings.TrimSuffix(string(w.buf[:i]), "\r"))
+		w.buf = w.buf[i+1:]
+	}
+
+	return len(p), nil
+}
+
+// Flush calls the function with the remaining bytes that do not end with a new line.
+func (w *lineWriter) Flush() {
+	if len(w.buf) == 0 {
+		return
+	}
+
+	w.onLine(strings.TrimSuffix(string(w.buf), "\r"))
+	w.buf = w.buf[:0]
+}
+
+// syncWriter is a buffer that can be written by multiple goroutines.
+type syncWriter struct {
+	mu  sync.Mutex
+	buf *bytes.Buffer
+}
+
+// newSyncWriter creates a new syncWriter that writes to the buffer.
+func newSyncWriter(buf *bytes.Buffer) *syncWriter {
+	w := syncWriter{
+		buf: buf,
+	}
+
+	return &w
+}
+
+// Write writes the bytes to the buffer.
+func (w *syncWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	return w.buf.Write(p)
+}
+
+// Bytes returns the bytes written to the buffer.
+func (w *syncWriter) Bytes() []byte {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	return w.buf.Bytes()
+}
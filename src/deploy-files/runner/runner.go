@@ -0,0 +1,476 @@
+package runner
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"slices"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/bobllor/macdeploy/src/deploy-files/scripts"
+)
+
+// dryRunPrefix is the prefix of every line printed during a dry run.
+const dryRunPrefix string = "[DRYRUN]"
+
+// killDelay is the time a canceled command has to exit before it is killed.
+// Commands are canceled with SIGTERM first, which sudo passes on to its child.
+const killDelay time.Duration = 10 * time.Second
+
+// ErrTimeout is returned when a command is stopped by its time limit.
+var ErrTimeout = errors.New("command timed out")
+
+// Runner runs the commands of the deployment. The commands are given as an argument
+// list, they are not interpreted by a shell unless the command is a shell.
+type Runner interface {
+	// Exec runs the command and returns its outputs.
+	Exec(cmd Cmd) (Result, error)
+
+	// DryRun returns true if the commands are printed instead of executed.
+	DryRun() bool
+
+	// Planf prints an action that is not a command during a dry run.
+	Planf(format string, v ...any)
+}
+
+// Redactor masks the secrets of a message, the secrets are registered with the logger.
+type Redactor interface {
+	Redact(msg string) string
+}
+
+// Cmd is a command given to a Runner.
+type Cmd struct {
+	// Name is the program that is ran, it is looked up in the PATH.
+	Name string
+	Args []string
+
+	// Stdin is given to the standard input of the command. It is used to give
+	// secrets to a command without them being in the arguments.
+	Stdin string
+
+	// Env is the environment of the command, it replaces the environment of the binary.
+	// If nil, the environment of the binary is inherited.
+	Env []string
+
+	// Timeout is the time limit of the command, it is used instead of the time limit
+	// of the Runner. Zero uses the time limit of the Runner.
+	Timeout time.Duration
+
+	// OnLine is called with each line of the output while the command runs, stderr
+	// is true for the lines of the standard error. The secrets are masked in the lines.
+	OnLine func(line string, stderr bool)
+
+	// Query marks a command that only reads the state of the device, it is
+	// executed during a dry run.
+	Query bool
+}
+
+// NewCmd creates a new Cmd of the program and its arguments.
+func NewCmd(name string, args ...string) Cmd {
+	return Cmd{Name: name, Args: args}
+}
+
+// Result are the outputs of a command.
+type Result struct {
+	Stdout []byte
+	Stderr []byte
+
+	// Combined is the standard output and standard error in the order they were written.
+	Combined []byte
+}
+
+// System is the Runner that executes the commands on the device. If dry run is enabled,
+// then the commands are printed instead of executed.
+type System struct {
+	// ctx is the context of the commands, the commands are canceled with it.
+	ctx context.Context
+
+	*state
+}
+
+// state is the configuration and the commands of a System, it is shared with the
+// Systems created from it with WithContext.
+type state struct {
+	dryRun   bool
+	out      io.Writer
+	redactor Redactor
+
+	// timeout is the time limit of each command, zero is no limit.
+	timeout time.Duration
+
+	mu sync.Mutex
+	// running are the commands that are running, used by the watchdog.
+	running map[int]Command
+	nextID  int
+	// timedOut are the printable commands that reached their time limit.
+	timedOut []string
+}
+
+// Command is a command that is running.
+type Command struct {
+	// Command is the printable form of the command.
+	Command string
+	Started time.Time
+
+	// Pid is the process ID of the command, it is also the ID of its process group.
+	Pid int
+}
+
+// New creates a new System that executes commands and prints to stdout.
+func New() *System {
+	runner := System{
+		ctx: context.Background(),
+		state: &state{
+			dryRun:   false,
+			out:      os.Stdout,
+			running:  make(map[int]Command),
+			timedOut: make([]string, 0),
+		},
+	}
+
+	return &runner
+}
+
+// WithContext returns a System that runs its commands with the context instead of the
+// context of r. The configuration and the running commands are shared with r.
+//
+// It is used by commands that run beside the stages, which are not stopped by the
+// context of a stage.
+func (r *System) WithContext(ctx context.Context) *System {
+	return &System{ctx: ctx, state: r.state}
+}
+
+// SetDryRun enables or disables the dry run.
+func (r *System) SetDryRun(dryRun bool) {
+	r.dryRun = dryRun
+}
+
+// DryRun returns true if dry run is enabled.
+func (r *System) DryRun() bool {
+	return r.dryRun
+}
+
+// SetRedactor sets the Redactor that masks the secrets of the printed commands and the
+// lines of the outputs. Nothing is masked if it is not set.
+func (r *System) SetRedactor(redactor Redactor) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.redactor = redactor
+}
+
+// SetContext sets the context of the commands. Running commands are stopped
+// once the context is canceled or its deadline is reached.
+//
+// Only the context of r is replaced, the Systems created with WithContext keep their context.
+func (r *System) SetContext(ctx context.Context) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.ctx = ctx
+}
+
+// SetTimeout sets the time limit of each command. Zero disables the limit.
+func (r *System) SetTimeout(timeout time.Duration) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.timeout = timeout
+}
+
+// Running returns the commands that are running, the oldest command is first.
+func (r *System) Running() []Command {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	commands := make([]Command, 0, len(r.running))
+	for _, command := range r.running {
+		commands = append(commands, command)
+	}
+
+	slices.SortFunc(commands, func(a, b Command) int {
+		return a.Started.Compare(b.Started)
+	})
+
+	return commands
+}
+
+// TimedOut returns the printable commands that reached their time limit.
+func (r *System) TimedOut() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return slices.Clone(r.timedOut)
+}
+
+// Exec runs the command with the context and time limit of the System and returns its outputs.
+// The lines of the outputs are given to the OnLine function of the command while it runs.
+// During a dry run, the command is printed and empty outputs are returned, unless it is a query.
+//
+// If the command is stopped by the time limit, then the error wraps ErrTimeout.
+// If the context is canceled, then the error wraps the context error.
+//
+// The command runs in its own process group so that a time limit stops the children of a
+// script with it. The group is in the background of the terminal, a process that reads the
+// terminal is stopped until its time limit. Because of this, sudo is always ran with -n unless
+// it reads the password from the standard input with -S, it fails instead of prompting
+// for the password.
+func (r *System) Exec(c Cmd) (Result, error) {
+	if r.dryRun && !c.Query {
+		r.printCommand(c.Name, c.Args)
+		return Result{}, nil
+	}
+
+	r.mu.Lock()
+	ctx := r.ctx
+	timeout := r.timeout
+	r.mu.Unlock()
+
+	if c.Timeout > 0 {
+		timeout = c.Timeout
+	}
+
+	cancel := func() {}
+	if timeout > 0 {
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+	}
+	defer cancel()
+
+	// the command runs in its own process group, the children of a script
+	// are stopped with it.
+	cmd := exec.CommandContext(ctx, c.Name, nonInteractive(c.Name, c.Args)...)
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	cmd.Cancel = func() error {
+		err := syscall.Kill(-cmd.Process.Pid, syscall.SIGTERM)
+		// a process stopped by reading the terminal receives the SIGTERM once it continues.
+		syscall.Kill(-cmd.Process.Pid, syscall.SIGCONT)
+
+		return err
+	}
+	cmd.WaitDelay = killDelay
+	cmd.Env = c.Env
+	if c.Stdin != "" {
+		cmd.Stdin = strings.NewReader(c.Stdin)
+	}
+
+	// the lines of the standard output and error are written by separate goroutines.
+	var mu sync.Mutex
+	onLine := func(stderr bool) func(string) {
+		return func(line string) {
+			if c.OnLine == nil {
+				return
+			}
+
+			mu.Lock()
+			defer mu.Unlock()
+
+			c.OnLine(r.maskSecrets(line), stderr)
+		}
+	}
+
+	stdout := bytes.Buffer{}
+	stderr := bytes.Buffer{}
+	combined := newSyncWriter(&bytes.Buffer{})
+	stdoutLines := newLineWriter(onLine(false))
+	stderrLines := newLineWriter(onLine(true))
+
+	cmd.Stdout = io.MultiWriter(&stdout, combined, stdoutLines)
+	cmd.Stderr = io.MultiWriter(&stderr, combined, stderrLines)
+
+	command := r.Format(c.Name, c.Args...)
+
+	err := cmd.Start()
+	if err == nil {
+		id := r.track(command, cmd.Process.Pid)
+		err = cmd.Wait()
+		r.untrack(id)
+	}
+
+	stdoutLines.Flush()
+	stderrLines.Flush()
+
+	res := Result{
+		Stdout:   stdout.Bytes(),
+		Stderr:   stderr.Bytes(),
+		Combined: combined.Bytes(),
+	}
+
+	if err != nil && ctx.Err() != nil {
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			r.mu.Lock()
+			r.timedOut = append(r.timedOut, command)
+			r.mu.Unlock()
+
+			return res, fmt.Errorf("%w: %s: %w", ErrTimeout, command, ctx.Err())
+		}
+
+		return res, fmt.Errorf("command %s stopped: %w", command, ctx.Err())
+	}
+
+	return res, err
+}
+
+// track adds the command with its process ID to the running commands and returns its ID.
+func (r *System) track(command string, pid int) int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.nextID += 1
+	r.running[r.nextID] = Command{Command: command, Started: time.Now(), Pid: pid}
+
+	return r.nextID
+}
+
+// untrack removes the command from the running commands.
+func (r *System) untrack(id int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	delete(r.running, id)
+}
+
+// Planf prints an action that is not a command, such as a file copy or a request.
+// It only prints during a dry run.
+func (r *System) Planf(format string, v ...any) {
+	if !r.dryRun {
+		return
+	}
+
+	msg := r.maskSecrets(fmt.Sprintf(format, v...))
+	fmt.Fprintf(r.out, "%s %s\n", dryRunPrefix, msg)
+}
+
+// Format returns the printable form of the command. Embedded scripts are shown
+// by their file name and the secrets are masked.
+func (r *System) Format(name string, args ...string) string {
+	return r.maskSecrets(Format(name, args...))
+}
+
+// Format returns the printable form of the command. Embedded scripts are shown
+// by their file name.
+func Format(name string, args ...string) string {
+	parts := []string{quote(name)}
+
+	for i, arg := range args {
+		// multi-line scripts given to bash are too long to print.
+		if i > 0 && args[i-1] == "-c" && strings.Contains(arg, "\n") {
+			scriptName := scripts.ScriptName(arg)
+			if scriptName == "" {
+				scriptName = "inline script"
+			}
+
+			parts = append(parts, fmt.Sprintf("<%s>", scriptName))
+			continue
+		}
+
+		parts = append(parts, quote(arg))
+	}
+
+	return strings.Join(parts, " ")
+}
+
+// nonInteractive returns the arguments of the command with -n added if the command is sudo
+// and its options do not have -S or -n. Only the options before the sudo command are checked.
+func nonInteractive(name string, args []string) []string {
+	if name != "sudo" {
+		return args
+	}
+
+	for _, arg := range args {
+		if !strings.HasPrefix(arg, "-") {
+			break
+		}
+		if arg == "-S" || arg == "-n" || arg == "--stdin" || arg == "--non-interactive" {
+			return args
+		}
+	}
+
+	return append([]string{"-n"}, args...)
+}
+
+// printCommand prints the command with the dry run prefix.
+func (r *System) printCommand(name string, args []string) {
+	fmt.Fprintf(r.out, "%s %s\n", dryRunPrefix, r.Format(name, args...))
+}
+
+// maskSecrets replaces all secrets found in the string.
+func (r *System) maskSecrets(str string) string {
+	r.mu.Lock()
+	redactor := r.redactor
+	r.mu.Unlock()
+
+	if redactor == nil {
+		return str
+	}
+
+	return redactor.Redact(str)
+}
+
+// Output runs the command with the Runner and returns its standard output.
+func Output(r Runner, name string, args ...string) ([]byte, error) {
+	res, err := r.Exec(NewCmd(name, args...))
+
+	return res.Stdout, err
+}
+
+// CombinedOutput runs the command with the Runner and returns its combined
+// standard output and standard error.
+func CombinedOutput(r Runner, name string, args ...string) ([]byte, error) {
+	res, err := r.Exec(NewCmd(name, args...))
+
+	return res.Combined, err
+}
+
+// Query runs the command that only reads the state of the device with the Runner and
+// returns its standard output. The command is also executed during a dry run.
+func Query(r Runner, name string, args ...string) ([]byte, error) {
+	cmd := NewCmd(name, args...)
+	cmd.Query = true
+
+	res, err := r.Exec(cmd)
+
+	return res.Stdout, err
+}
+
+// Run runs the command with the Runner and waits for it to complete.
+func Run(r Runner, name string, args ...string) error {
+	_, err := r.Exec(NewCmd(name, args...))
+
+	return err
+}
+
+// ExitCode returns the exit code of the command from its error. Zero is returned
+// for a nil error and -1 is returned if the command did not exit by itself.
+func ExitCode(err error) int {
+	if err == nil {
+		return 0
+	}
+
+	var exitErr interface{ ExitCode() int }
+	if errors.As(err, &exitErr) {
+		return exitErr.ExitCode()
+	}
+
+	return -1
+}
+
+// quote quotes the argument with single quotes if it contains characters
+// that are interpreted by the shell.
+func quote(arg string) string {
+	if arg == "" {
+		return "''"
+	}
+
+	if !strings.ContainsAny(arg, " \t\n'\"\\$`|&;<>()*?[]{}!#~") {
+		return arg
+	}
+
+	return "'" + strings.ReplaceAll(arg, "'", `'\''`) + "'"
+}
@@ -0,0 +1,291 @@
+package runner
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/bobllor/assert"
+	"github.com/bobllor/macdeploy/src/deploy-files/logger"
+	"github.com/bobllor/macdeploy/src/deploy-files/scripts"
+)
+
+// newTestRunner returns a dry run System that prints to a buffer.
+func newTestRunner() (*System, *bytes.Buffer) {
+	buf := &bytes.Buffer{}
+
+	r := New()
+	r.out = buf
+	r.SetDryRun(true)
+
+	return r, buf
+}
+
+func TestFormat(t *testing.T) {
+	r := New()
+
+	cases := map[string][]string{
+		"sudo softwareupdate --install-rosetta":       {"sudo", "softwareupdate", "--install-rosetta"},
+		"bash -c 'sudo installer -pkg '\\''a b'\\'''": {"bash", "-c", "sudo installer -pkg 'a b'"},
+		"echo ''": {"echo", ""},
+	}
+
+	for expected, cmd := range cases {
+		assert.Equal(t, r.Format(cmd[0], cmd[1:]...), expected)
+	}
+
+	t.Run("Embedded Script", func(t *testing.T) {
+		script := scripts.NewScript()
+
+		out := r.Format("sudo", "bash", "-c", script.EnableFirewall)
+		assert.Equal(t, out, "sudo bash -c <enable_firewall.sh>")
+	})
+
+	t.Run("Inline Script", func(t *testing.T) {
+		out := r.Format("bash", "-c", "echo one\necho two")
+		assert.Equal(t, out, "bash -c <inline script>")
+	})
+}
+
+func TestMaskSecrets(t *testing.T) {
+	r, buf := newTestRunner()
+	log := logger.NewTestLogger()
+	log.AddSecrets("hunter2", "")
+	r.SetRedactor(log)
+
+	_, err := Output(r, "bash", "-c", "sudo -S echo <<< 'hunter2'")
+	assert.Nil(t, err)
+	assert.Equal(t, strings.Contains(buf.String(), "hunter2"), false)
+	assert.Equal(t, strings.Contains(buf.String(), "********"), true)
+
+	buf.Reset()
+	r.Planf("login with %s", "hunter2")
+	assert.Equal(t, buf.String(), dryRunPrefix+" login with ********\n")
+}
+
+func TestDryRun(t *testing.T) {
+	r, buf := newTestRunner()
+
+	file := filepath.Join(t.TempDir(), "dryrun.txt")
+
+	out, err := CombinedOutput(r, "touch", file)
+	assert.Nil(t, err)
+	assert.Equal(t, len(out), 0)
+	assert.Nil(t, Run(r, "touch", file))
+
+	_, err = os.Stat(file)
+	assert.NotNil(t, err)
+
+	out, err = Query(r, "echo", "query")
+	assert.Nil(t, err)
+	assert.Equal(t, string(out), "query\n")
+
+	assert.Equal(t, buf.String(), strings.Repeat(dryRunPrefix+" touch "+file+"\n", 2))
+}
+
+func TestRun(t *testing.T) {
+	r, buf := newTestRunner()
+	r.SetDryRun(false)
+
+	out, err := Output(r, "echo", "hello")
+	assert.Nil(t, err)
+	assert.Equal(t, string(out), "hello\n")
+
+	out, err = CombinedOutput(r, "bash", "-c", "echo one; sleep 0.1; echo two >&2")
+	assert.Nil(t, err)
+	assert.Equal(t, string(out), "one\ntwo\n")
+
+	r.Planf("not printed")
+	assert.Equal(t, buf.Len(), 0)
+}
+
+func TestExec(t *testing.T) {
+	r := New()
+
+	cmd := NewCmd("bash", "-c", `echo "$MACDEPLOY_TEST"`)
+	cmd.Env = []string{"MACDEPLOY_TEST=one two"}
+
+	res, err := r.Exec(cmd)
+	assert.Nil(t, err)
+	assert.Equal(t, strings.TrimSpace(string(res.Stdout)), "one two")
+
+	t.Run("Inherit", func(t *testing.T) {
+		t.Setenv("MACDEPLOY_TEST", "three")
+
+		out, err := Output(r, "bash", "-c", `echo "$MACDEPLOY_TEST"`)
+		assert.Nil(t, err)
+		assert.Equal(t, strings.TrimSpace(string(out)), "three")
+	})
+
+	t.Run("Stdin", func(t *testing.T) {
+		cmd := NewCmd("cat")
+		cmd.Stdin = "it's a $secret"
+
+		res, err := r.Exec(cmd)
+		assert.Nil(t, err)
+		assert.Equal(t, string(res.Stdout), "it's a $secret")
+	})
+
+	t.Run("Timeout", func(t *testing.T) {
+		r.SetTimeout(time.Minute)
+
+		cmd := NewCmd("sleep", "5")
+		cmd.Timeout = 50 * time.Millisecond
+
+		_, err := r.Exec(cmd)
+		assert.Equal(t, errors.Is(err, ErrTimeout), true)
+	})
+
+	t.Run("Exit Code", func(t *testing.T) {
+		err := Run(r, "bash", "-c", "exit 3")
+		assert.Equal(t, ExitCode(err), 3)
+		assert.Equal(t, ExitCode(nil), 0)
+		assert.Equal(t, ExitCode(errors.New("not found")), -1)
+	})
+}
+
+func TestStream(t *testing.T) {
+	r := New()
+	log := logger.NewTestLogger()
+	log.AddSecrets("hunter2")
+	r.SetRedactor(log)
+
+	lines := []string{}
+	cmd := NewCmd("bash", "-c", "echo one; sleep 0.1; echo two >&2; sleep 0.1; printf 'hunter2'")
+	cmd.OnLine = func(line string, stderr bool) {
+		if stderr {
+			line = "stderr: " + line
+		}
+
+		lines = append(lines, line)
+	}
+
+	res, err := r.Exec(cmd)
+	assert.Nil(t, err)
+
+	assert.Equal(t, string(res.Stdout), "one\nhunter2")
+	assert.Equal(t, string(res.Stderr), "two\n")
+	assert.Equal(t, strings.Join(lines, ","), "one,stderr: two,********")
+}
+
+func TestTimeout(t *testing.T) {
+	r := New()
+	r.SetTimeout(100 * time.Millisecond)
+
+	start := time.Now()
+	err := Run(r, "sleep", "5")
+
+	assert.NotNil(t, err)
+	assert.Equal(t, errors.Is(err, ErrTimeout), true)
+	assert.Equal(t, errors.Is(err, context.DeadlineExceeded), true)
+	assert.Equal(t, time.Since(start) < 5*time.Second, true)
+	assert.Equal(t, strings.Join(r.TimedOut(), ","), "sleep 5")
+	assert.Equal(t, len(r.Running()), 0)
+
+	t.Run("Stopped Process", func(t *testing.T) {
+		// the same state as a background process that reads the terminal.
+		start := time.Now()
+		cmd := NewCmd("bash", "-c", "kill -STOP $$; sleep 5")
+		cmd.Timeout = 200 * time.Millisecond
+
+		_, err := r.Exec(cmd)
+		assert.Equal(t, errors.Is(err, ErrTimeout), true)
+		assert.Equal(t, time.Since(start) < killDelay, true)
+	})
+
+	t.Run("No Timeout", func(t *testing.T) {
+		r.SetTimeout(0)
+
+		assert.Nil(t, Run(r, "true"))
+	})
+}
+
+func TestContextCanceled(t *testing.T) {
+	r := New()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	r.SetContext(ctx)
+
+	go func() {
+		for len(r.Running()) == 0 {
+			time.Sleep(10 * time.Millisecond)
+		}
+
+		assert.Equal(t, r.Running()[0].Command, "sleep 5")
+		cancel()
+	}()
+
+	err := Run(r, "sleep", "5")
+
+	assert.NotNil(t, err)
+	assert.Equal(t, errors.Is(err, context.Canceled), true)
+	assert.Equal(t, errors.Is(err, ErrTimeout), false)
+	assert.Equal(t, len(r.TimedOut()), 0)
+}
+
+func TestKillProcessGroup(t *testing.T) {
+	r := New()
+
+	go func() {
+		for len(r.Running()) == 0 {
+			time.Sleep(10 * time.Millisecond)
+		}
+
+		pid := r.Running()[0].Pid
+		assert.NotEqual(t, pid, 0)
+		assert.Nil(t, syscall.Kill(-pid, syscall.SIGKILL))
+	}()
+
+	// the output is held open by the child, it must be killed with its parent.
+	start := time.Now()
+	err := Run(r, "bash", "-c", "sleep 5 & wait")
+
+	assert.NotNil(t, err)
+	assert.Equal(t, time.Since(start) < 2*time.Second, true)
+	assert.Equal(t, len(r.Running()), 0)
+}
+
+func TestWithContext(t *testing.T) {
+	r := New()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	r.SetContext(ctx)
+	cancel()
+
+	// the commands of the view are not stopped by the canceled context of r.
+	view := r.WithContext(context.Background())
+	view.SetTimeout(50 * time.Millisecond)
+
+	err := Run(view, "sleep", "5")
+
+	assert.Equal(t, errors.Is(err, ErrTimeout), true)
+	assert.Equal(t, len(r.TimedOut()), 1)
+
+	err = Run(r, "true")
+	assert.Equal(t, errors.Is(err, context.Canceled), true)
+}
+
+func TestNonInteractive(t *testing.T) {
+	cases := map[string]string{
+		"sudo installer -pkg a.pkg":  "-n installer -pkg a.pkg",
+		"sudo -S -v":                 "-S -v",
+		"sudo -k -S -v":              "-k -S -v",
+		"sudo -n true":               "-n true",
+		"sudo -K":                    "-n -K",
+		"sudo bash -c echo -S":       "-n bash -c echo -S",
+		"installer -pkg a.pkg -S -n": "-pkg a.pkg -S -n",
+	}
+
+	for command, expected := range cases {
+		fields := strings.Fields(command)
+
+		args := nonInteractive(fields[0], fields[1:])
+		assert.Equal(t, strings.Join(args, " "), expected)
+	}
+}
@@ -4,13 +4,18 @@
 # Args:
 #   - 0: The user's display name, or Full Name.
 #   - 1: The account name used internally.
-#   - 2: The user's password.
-#   - 3: String boolean used to grant admin to the user.
+#   - 2: String boolean used to grant admin to the user.
+# Stdin:
+#   - The user's password.
+#
+# sysadminctl prompts on the terminal for a password given as '-', which is stopped in the
+# background process group of the deployment. The password is given as an argument instead.
 
 full_name=$0
 account_name=$1
-password=$2
-isAdmin=$3
+isAdmin=$2
+
+IFS= read -r password
 
 if [[ $isAdmin == "false" ]]; then
     sudo sysadminctl -addUser "$account_name" \
@@ -18,4 +23,4 @@ if [[ $isAdmin == "false" ]]; then
 else
     sudo sysadminctl -addUser "$account_name" \
         -fullName "$full_name" -password "$password" -admin
-fi
\ No newline at end of file
+fi
@@ -10,40 +10,45 @@ import (
 //go:embed create_user.sh
 var createUserScript string
 
-//go:embed enable_filevault.sh
-var enableFileVaultScript string
-
 //go:embed enable_firewall.sh
 var enableFirewallScript string
 
 //go:embed find_files.sh
 var findFilesScript string
 
-//go:embed disable_filevault.sh
-var disableFileVaultScript string
-
-//go:embed change_filevault.sh
-var changeFileVaultScript string
+//go:embed secure_token.sh
+var secureTokenScript string
 
+// BashScripts are the embedded scripts. The secrets of the scripts are read
+// from the standard input, they are never given as arguments.
 type BashScripts struct {
-	CreateUser         string
-	EnableFileVault    string
-	DisableFileVault   string
-	EnableFirewall     string
-	FindFiles          string // Takes two arguments: search_dir, ext_type
-	ChangeFileVaultKey string
+	CreateUser     string
+	EnableFirewall string
+	FindFiles      string // Takes two arguments: search_dir, ext_type
+	SecureToken    string
 }
 
 // NewScript generates an embedded struct for scripts created in Bash.
 func NewScript() *BashScripts {
 	scripts := BashScripts{
-		CreateUser:         createUserScript,
-		EnableFileVault:    enableFileVaultScript,
-		DisableFileVault:   disableFileVaultScript,
-		EnableFirewall:     enableFirewallScript,
-		FindFiles:          findFilesScript,
-		ChangeFileVaultKey: changeFileVaultScript,
+		CreateUser:     createUserScript,
+		EnableFirewall: enableFirewallScript,
+		FindFiles:      findFilesScript,
+		SecureToken:    secureTokenScript,
 	}
 
 	return &scripts
 }
+
+// ScriptName returns the file name of an embedded script from its content.
+// An empty string is returned if the content is not an embedded script.
+func ScriptName(content string) string {
+	names := map[string]string{
+		createUserScript:     "create_user.sh",
+		enableFirewallScript: "enable_firewall.sh",
+		findFilesScript:      "find_files.sh",
+		secureTokenScript:    "secure_token.sh",
+	}
+
+	return names[content]
+}
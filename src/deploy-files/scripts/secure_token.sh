@@ -0,0 +1,21 @@
+#!/bin/bash
+
+# Adds the Secure Token to the user, which allows the user to unlock FileVault.
+# Args:
+#   - 0: The account name of the user.
+#   - 1: The username of the admin account.
+# Stdin:
+#   - The user's password on the first line.
+#   - The admin's password on the second line.
+#
+# sysadminctl prompts on the terminal for a password given as '-', which is stopped in the
+# background process group of the deployment. The passwords are given as arguments instead.
+
+account_name=$0
+admin_user=$1
+
+IFS= read -r password
+IFS= read -r admin_password
+
+sudo sysadminctl -secureTokenOn "$account_name" -password "$password" \
+    -adminUser "$admin_user" -adminPassword "$admin_password"
@@ -46,3 +46,58 @@ func TestExecuteHelperScriptFindFiles(t *testing.T) {
 		t.Errorf("failed to find files: got %d, expected %d", len(outArr), baseCount)
 	}
 }
+
+// stubSudo adds a sudo to the PATH that prints its arguments, one per line.
+func stubSudo(t *testing.T) {
+	dir := t.TempDir()
+
+	err := os.WriteFile(dir+"/sudo", []byte("#!/bin/bash\nprintf '%s\\n' \"$@\"\n"), 0o755)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Setenv("PATH", dir+":"+os.Getenv("PATH"))
+}
+
+func TestCreateUserScriptArguments(t *testing.T) {
+	stubSudo(t)
+
+	password := `it's a "$HOME" pass`
+	cmd := exec.Command("bash", "-c", script.CreateUser, "John O'Brien $USER", "john.obrien", "true")
+	cmd.Stdin = strings.NewReader(password + "\n")
+
+	out, err := cmd.Output()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []string{
+		"sysadminctl", "-addUser", "john.obrien",
+		"-fullName", "John O'Brien $USER", "-password", password, "-admin",
+	}
+	args := strings.Split(strings.TrimSuffix(string(out), "\n"), "\n")
+	if strings.Join(args, "|") != strings.Join(expected, "|") {
+		t.Errorf("got arguments %q, expected %q", args, expected)
+	}
+}
+
+func TestSecureTokenScriptArguments(t *testing.T) {
+	stubSudo(t)
+
+	cmd := exec.Command("bash", "-c", script.SecureToken, "john.doe", "admin user")
+	cmd.Stdin = strings.NewReader("user 'pass'\nadmin $(pass)\n")
+
+	out, err := cmd.Output()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []string{
+		"sysadminctl", "-secureTokenOn", "john.doe", "-password", "user 'pass'",
+		"-adminUser", "admin user", "-adminPassword", "admin $(pass)",
+	}
+	args := strings.Split(strings.TrimSuffix(string(out), "\n"), "\n")
+	if strings.Join(args, "|") != strings.Join(expected, "|") {
+		t.Errorf("got arguments %q, expected %q", args, expected)
+	}
+}
@@ -0,0 +1,22 @@
+package requests
+
+import "github.com/bobllor/macdeploy/src/deploy-files/report"
+
+type ReportPayload struct {
+	SerialTag string         `json:"serialTag"`
+	Report    *report.Report `json:"report"`
+}
+
+// NewReportPayload creates a new ReportPayload with the deployment report.
+func NewReportPayload(report *report.Report) *ReportPayload {
+	payload := ReportPayload{
+		SerialTag: "",
+		Report:    report,
+	}
+
+	return &payload
+}
+
+func (r *ReportPayload) SetBody(content string) {
+	r.SerialTag = content
+}
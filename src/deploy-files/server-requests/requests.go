@@ -2,6 +2,7 @@ package requests
 
 import (
 	"bytes"
+	"context"
 	"crypto/tls"
 	"encoding/json"
 	"errors"
@@ -12,6 +13,7 @@ import (
 	"time"
 
 	"github.com/bobllor/macdeploy/src/deploy-files/logger"
+	"github.com/bobllor/macdeploy/src/deploy-files/runner"
 )
 
 type Payload interface {
@@ -26,9 +28,15 @@ type Response struct {
 type Request struct {
 	client *http.Client
 	log    *logger.Logger
+	runner runner.Runner
+
+	// ctx is the context of the requests, the requests are canceled with it.
+	ctx context.Context
 }
 
-func NewRequest(log *logger.Logger) *Request {
+// NewRequest creates a new Request. The requests are canceled with the context, and
+// are printed instead of sent if the Runner is in a dry run.
+func NewRequest(ctx context.Context, log *logger.Logger, runner runner.Runner) *Request {
 	// used to bypass the unverified check due to no CA
 	tls := &http.Transport{
 		TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
@@ -37,6 +45,8 @@ func NewRequest(log *logger.Logger) *Request {
 	request := Request{
 		client: &http.Client{Transport: tls},
 		log:    log,
+		runner: runner,
+		ctx:    ctx,
 	}
 
 	return &request
@@ -78,7 +88,18 @@ type DeviceQuery struct {
 func (r *Request) GetDeviceKeyInfo(host string, deviceTag string) (*DeviceQuery, error) {
 	url := host + "/api/devices/" + deviceTag
 
-	res, err := r.client.Get(url)
+	// no entries are returned during a dry run, the FileVault process is always attempted.
+	if r.runner.DryRun() {
+		r.runner.Planf("GET %s", url)
+		return &DeviceQuery{Content: []DeviceFileData{}, Status: StatusTypeSuccess}, nil
+	}
+
+	req, err := http.NewRequestWithContext(r.ctx, "GET", url, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := r.client.Do(req)
 	if err != nil {
 		return nil, err
 	}
@@ -98,6 +119,64 @@ func (r *Request) GetDeviceKeyInfo(host string, deviceTag string) (*DeviceQuery,
 	return devRes, nil
 }
 
+// configTimeout is the time limit of the device config request, the deployment
+// must not wait on an unreachable server.
+const configTimeout time.Duration = 10 * time.Second
+
+type ConfigQuery struct {
+	Content    map[string]any `json:"content"`
+	Message    string         `json:"message"`
+	Status     StatusType     `json:"status"`
+	StatusCode int            `json:"status_code"`
+}
+
+// GetDeviceConfig sends a GET request to the url with the device tag to retrieve
+// the config overlay of the device in a ConfigQuery.
+//
+// The content of the ConfigQuery is empty if the device does not have a config overlay.
+//
+// The host is expected to the root URL connection to access the server.
+func (r *Request) GetDeviceConfig(host string, deviceTag string) (*ConfigQuery, error) {
+	url := host + "/api/config/" + deviceTag
+
+	// no overlay is used during a dry run.
+	if r.runner.DryRun() {
+		r.runner.Planf("GET %s", url)
+		return &ConfigQuery{Content: map[string]any{}, Status: StatusTypeSuccess}, nil
+	}
+
+	ctx, cancel := context.WithTimeout(r.ctx, configTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := r.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode >= 400 {
+		return nil, fmt.Errorf("failed to query device config (%s)", res.Status)
+	}
+
+	configRes := &ConfigQuery{}
+
+	err = json.NewDecoder(res.Body).Decode(configRes)
+	if err != nil {
+		return nil, err
+	}
+
+	if configRes.Content == nil {
+		configRes.Content = map[string]any{}
+	}
+
+	return configRes, nil
+}
+
 // POSTData sends a JSON POST request to the server.
 // If a trailing/leading slash exists on the host/endpoint respectively or if either is empty,
 // an error will be returned.
@@ -116,6 +195,11 @@ func (r *Request) POSTData(host string, endpoint string, payload Payload) (*Resp
 	// validated from above
 	url := host + endpoint
 
+	if r.runner.DryRun() {
+		r.runner.Planf("POST %s", url)
+		return &Response{Status: string(StatusTypeSuccess)}, nil
+	}
+
 	jsonStr, err := json.Marshal(payload)
 	if err != nil {
 		return nil, err
@@ -155,7 +239,17 @@ func (r *Request) POSTData(host string, endpoint string, payload Payload) (*Resp
 func (r *Request) VerifyConnection(host string) (bool, error) {
 	r.log.Debugf("Host: %s", host)
 
-	resp, err := r.client.Get(host)
+	if r.runner.DryRun() {
+		r.runner.Planf("GET %s", host)
+		return true, nil
+	}
+
+	req, err := http.NewRequestWithContext(r.ctx, "GET", host, nil)
+	if err != nil {
+		return false, err
+	}
+
+	resp, err := r.client.Do(req)
 	if err != nil {
 		return false, err
 	}
@@ -169,7 +263,7 @@ func (r *Request) VerifyConnection(host string) (bool, error) {
 
 // newJSONRequest creates a new HTTP request object.
 func (r *Request) newJSONRequest(url string, jsonStr []byte) (*http.Request, error) {
-	req, err := http.NewRequest("POST", url, bytes.NewBuffer(jsonStr))
+	req, err := http.NewRequestWithContext(r.ctx, "POST", url, bytes.NewBuffer(jsonStr))
 	if err != nil {
 		return nil, err
 	}
@@ -2,6 +2,7 @@ package requests
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"net/http"
@@ -13,6 +14,7 @@ import (
 
 	"github.com/bobllor/assert"
 	"github.com/bobllor/macdeploy/src/deploy-files/logger"
+	"github.com/bobllor/macdeploy/src/deploy-files/runner"
 )
 
 // testNoIntegrationSerial is a serial used only for non-integration testing.
@@ -37,7 +39,7 @@ func TestGetQueryDataNormalNoServer(t *testing.T) {
 	defer serv.Close()
 
 	mux.HandleFunc("GET /api/devices/{device}", testQueryFunc)
-	req := NewRequest(logger.NewTestLogger())
+	req := NewRequest(context.Background(), logger.NewTestLogger(), runner.NewFake())
 
 	t.Run("Normal With Device", func(t *testing.T) {
 
@@ -56,6 +58,81 @@ func TestGetQueryDataNormalNoServer(t *testing.T) {
 	})
 }
 
+func TestGetDeviceConfigNoServer(t *testing.T) {
+	mux := http.NewServeMux()
+	serv := httptest.NewServer(mux)
+	defer serv.Close()
+
+	mux.HandleFunc("GET /api/config/{device}", testConfigFunc)
+	req := NewRequest(context.Background(), logger.NewTestLogger(), runner.NewFake())
+
+	t.Run("Normal With Config", func(t *testing.T) {
+		configQ, err := req.GetDeviceConfig(serv.URL, testNoIntegrationSerial)
+		assert.Nil(t, err)
+
+		assert.Equal(t, configQ.Content["profile"], any("loaner"))
+	})
+
+	t.Run("Normal No Config", func(t *testing.T) {
+		configQ, err := req.GetDeviceConfig(serv.URL, "tester123")
+		assert.Nil(t, err)
+
+		assert.Equal(t, len(configQ.Content), 0)
+	})
+
+	t.Run("Server Error", func(t *testing.T) {
+		_, err := req.GetDeviceConfig(serv.URL, "error")
+		assert.NotNil(t, err)
+	})
+
+	t.Run("Unreachable Server", func(t *testing.T) {
+		closed := httptest.NewServer(http.NewServeMux())
+		closed.Close()
+
+		_, err := req.GetDeviceConfig(closed.URL, testNoIntegrationSerial)
+		assert.NotNil(t, err)
+	})
+}
+
+func TestRequestDryRun(t *testing.T) {
+	mux := http.NewServeMux()
+	serv := httptest.NewServer(mux)
+	defer serv.Close()
+
+	called := false
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		called = true
+	})
+
+	fake := runner.NewFake()
+	fake.SetDryRun(true)
+	req := NewRequest(context.Background(), logger.NewTestLogger(), fake)
+
+	_, err := req.POSTData(serv.URL, "/api/log", NewLogPayload("log.log"))
+	assert.Nil(t, err)
+	_, err = req.GetDeviceConfig(serv.URL, testNoIntegrationSerial)
+	assert.Nil(t, err)
+
+	assert.Equal(t, called, false)
+	assert.Equal(t, len(fake.Plans()), 2)
+	assert.Equal(t, fake.Plans()[0], "POST "+serv.URL+"/api/log")
+}
+
+func TestRequestCanceled(t *testing.T) {
+	mux := http.NewServeMux()
+	serv := httptest.NewServer(mux)
+	defer serv.Close()
+
+	mux.HandleFunc("GET /api/config/{device}", testConfigFunc)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	req := NewRequest(ctx, logger.NewTestLogger(), runner.NewFake())
+
+	_, err := req.GetDeviceConfig(serv.URL, testNoIntegrationSerial)
+	assert.NotNil(t, err)
+}
+
 func TestGetQueryDataFail(t *testing.T) {
 	mux := http.NewServeMux()
 	serv := httptest.NewServer(mux)
@@ -76,7 +153,7 @@ func TestGetQueryDataFail(t *testing.T) {
 
 func TestGetQueryDataIntegration(t *testing.T) {
 	log := logger.NewTestLogger()
-	req := NewRequest(log)
+	req := NewRequest(context.Background(), log, runner.NewFake())
 
 	dres, err := req.GetDeviceKeyInfo("https://127.0.0.1:5000", testSerial)
 	assert.Nil(t, err)
@@ -85,7 +162,7 @@ func TestGetQueryDataIntegration(t *testing.T) {
 
 func TestGetQueryDataNoDeviceIntegration(t *testing.T) {
 	log := logger.NewTestLogger()
-	req := NewRequest(log)
+	req := NewRequest(context.Background(), log, runner.NewFake())
 	serial := "DoesnotExist"
 
 	dres, err := req.GetDeviceKeyInfo("https://127.0.0.1:5000", serial)
@@ -96,7 +173,7 @@ func TestGetQueryDataNoDeviceIntegration(t *testing.T) {
 
 func TestSendKeyPayloadIntegration(t *testing.T) {
 	log := logger.NewTestLogger()
-	req := NewRequest(log)
+	req := NewRequest(context.Background(), log, runner.NewFake())
 	root := getProjectRoot(t) + "/testroot/keys"
 	// needed due to github actions debugging
 	fmt.Println("Debug root:", root)
@@ -249,3 +326,34 @@ func getProjectRoot(t *testing.T) string {
 
 	return strings.Join(newDir, "/")
 }
+
+func testConfigFunc(w http.ResponseWriter, r *http.Request) {
+	device := r.PathValue("device")
+
+	if device == "error" {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	cq := &ConfigQuery{
+		Content:    map[string]any{},
+		Status:     StatusTypeError,
+		StatusCode: http.StatusOK,
+	}
+
+	if strings.EqualFold(device, testNoIntegrationSerial) {
+		cq.Status = StatusTypeSuccess
+		cq.Content["profile"] = "loaner"
+	}
+
+	b, err := json.Marshal(cq)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	_, err = w.Write(b)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,160 @@
+package state
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"slices"
+	"time"
+
+	"github.com/bobllor/macdeploy/src/deploy-files/pipeline"
+)
+
+// Journal is the persisted state of a deployment. It is written after each stage
+// to allow an interrupted deployment to be resumed.
+type Journal struct {
+	SerialTag string    `json:"serial_tag"`
+	Started   time.Time `json:"started"`
+	Updated   time.Time `json:"updated"`
+
+	// Stages are the stages that have ran, in the order they finished.
+	Stages []StageEntry `json:"stages"`
+
+	AccountsCreated   []string `json:"accounts_created"`
+	PackagesInstalled []string `json:"packages_installed"`
+	KeyEscrowed       bool     `json:"key_escrowed"`
+	LogSent           bool     `json:"log_sent"`
+
+	// path is the file path of the journal.
+	path string
+}
+
+// StageEntry is the last known outcome of a stage.
+type StageEntry struct {
+	Name   string          `json:"name"`
+	Status pipeline.Status `json:"status"`
+	Error  string          `json:"error,omitempty"`
+}
+
+// NewJournal creates a new empty Journal that is saved to path.
+func NewJournal(path string, serialTag string) *Journal {
+	now := time.Now()
+
+	journal := Journal{
+		SerialTag:         serialTag,
+		Started:           now,
+		Updated:           now,
+		Stages:            make([]StageEntry, 0),
+		AccountsCreated:   make([]string, 0),
+		PackagesInstalled: make([]string, 0),
+		path:              path,
+	}
+
+	return &journal
+}
+
+// Load reads the Journal from path.
+//
+// An error is returned if the file cannot be read or parsed. If the file does
+// not exist, the error wraps os.ErrNotExist.
+func Load(path string) (*Journal, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	journal := NewJournal(path, "")
+
+	err = json.Unmarshal(data, journal)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse state journal %s: %v", path, err)
+	}
+
+	return journal, nil
+}
+
+// Remove deletes the Journal at path. No error is returned if it does not exist.
+func Remove(path string) error {
+	err := os.Remove(path)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	return nil
+}
+
+// Path returns the file path of the Journal.
+func (j *Journal) Path() string {
+	return j.path
+}
+
+// Save writes the Journal to its path.
+func (j *Journal) Save() error {
+	j.Updated = time.Now()
+
+	data, err := json.MarshalIndent(j, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	// the journal can contain account names, it is only readable by the owner.
+	return os.WriteFile(j.path, data, 0o600)
+}
+
+// SetStage records the result of a stage. Skipped stages are not recorded,
+// the previous outcome of the stage is kept instead.
+func (j *Journal) SetStage(result pipeline.Result) {
+	if result.Status == pipeline.StatusSkipped {
+		return
+	}
+
+	entry := StageEntry{
+		Name:   result.Name,
+		Status: result.Status,
+	}
+	if result.Err != nil {
+		entry.Error = result.Err.Error()
+	}
+
+	// the entry is moved to the end to keep the finished order.
+	j.Stages = slices.DeleteFunc(j.Stages, func(e StageEntry) bool {
+		return e.Name == result.Name
+	})
+	j.Stages = append(j.Stages, entry)
+}
+
+// Completed returns true if the stage has completed.
+func (j *Journal) Completed(name string) bool {
+	return slices.Contains(j.CompletedStages(), name)
+}
+
+// CompletedStages returns the names of the completed stages.
+func (j *Journal) CompletedStages() []string {
+	completed := []string{}
+
+	for _, entry := range j.Stages {
+		if entry.Status == pipeline.StatusCompleted {
+			completed = append(completed, entry.Name)
+		}
+	}
+
+	return completed
+}
+
+// AddAccounts records the created accounts.
+func (j *Journal) AddAccounts(accounts ...string) {
+	for _, account := range accounts {
+		if !slices.Contains(j.AccountsCreated, account) {
+			j.AccountsCreated = append(j.AccountsCreated, account)
+		}
+	}
+}
+
+// AddPackages records the installed packages.
+func (j *Journal) AddPackages(packages ...string) {
+	for _, pkg := range packages {
+		if !slices.Contains(j.PackagesInstalled, pkg) {
+			j.PackagesInstalled = append(j.PackagesInstalled, pkg)
+		}
+	}
+}
@@ -0,0 +1,67 @@
+package state
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/bobllor/assert"
+	"github.com/bobllor/macdeploy/src/deploy-files/pipeline"
+	"github.com/bobllor/macdeploy/src/tests"
+)
+
+func TestSaveAndLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state.json")
+
+	journal := NewJournal(path, "SERIAL")
+	journal.SetStage(pipeline.Result{Name: "accounts", Status: pipeline.StatusCompleted})
+	journal.SetStage(pipeline.Result{Name: "filevault", Status: pipeline.StatusFailed, Err: errors.New("failed")})
+	journal.AddAccounts("user", "user")
+	journal.AddPackages("package.pkg")
+	journal.LogSent = true
+
+	assert.Nil(t, journal.Save())
+
+	loaded, err := Load(path)
+	assert.Nil(t, err)
+
+	assert.Equal(t, loaded.SerialTag, "SERIAL")
+	assert.Equal(t, loaded.Path(), path)
+	assert.Equal(t, len(loaded.Stages), 2)
+	assert.Equal(t, loaded.Stages[1].Error, "failed")
+	assert.Equal(t, len(loaded.AccountsCreated), 1)
+	assert.Equal(t, loaded.PackagesInstalled[0], "package.pkg")
+	assert.Equal(t, loaded.LogSent, true)
+	assert.Equal(t, loaded.KeyEscrowed, false)
+
+	t.Run("Missing Journal", func(t *testing.T) {
+		_, err := Load(filepath.Join(t.TempDir(), "missing.json"))
+		tests.Checkf(t, !errors.Is(err, os.ErrNotExist), "expected a not exist error, got %v", err)
+	})
+
+	t.Run("Remove", func(t *testing.T) {
+		assert.Nil(t, Remove(path))
+		// removing a missing journal is not an error
+		assert.Nil(t, Remove(path))
+	})
+}
+
+func TestCompletedStages(t *testing.T) {
+	journal := NewJournal("", "SERIAL")
+
+	journal.SetStage(pipeline.Result{Name: "accounts", Status: pipeline.StatusFailed})
+	journal.SetStage(pipeline.Result{Name: "dmg", Status: pipeline.StatusCompleted})
+	journal.SetStage(pipeline.Result{Name: "packages", Status: pipeline.StatusCompleted})
+	assert.Equal(t, journal.Completed("accounts"), false)
+
+	// skipped stages keep the previous result
+	journal.SetStage(pipeline.Result{Name: "dmg", Status: pipeline.StatusSkipped})
+	journal.SetStage(pipeline.Result{Name: "accounts", Status: pipeline.StatusCompleted})
+
+	expected := []string{"dmg", "packages", "accounts"}
+	completed := journal.CompletedStages()
+	tests.Checkf(t, !slices.Equal(completed, expected), "expected %v, got %v", expected, completed)
+	assert.Equal(t, len(journal.Stages), 3)
+}
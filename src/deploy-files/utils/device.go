@@ -0,0 +1,51 @@
+package utils
+
+import (
+	"fmt"
+	"runtime"
+	"strings"
+
+	"github.com/bobllor/macdeploy/src/deploy-files/runner"
+)
+
+// Device are the facts of the device used to evaluate the package conditions.
+// A fact is empty if it could not be retrieved.
+type Device struct {
+	// Arch is the architecture of the CPU, arm64 or amd64.
+	Arch string
+	// OSVersion is the macOS version, e.g. 14.5.
+	OSVersion string
+	// Model is the model identifier, e.g. MacBookPro18,3 or Mac14,2.
+	Model string
+}
+
+// GetDevice retrieves the facts of the device. The facts that fail to be retrieved are left empty.
+//
+// The architecture is read from the hardware, an amd64 binary running under Rosetta
+// still reports arm64.
+func GetDevice(r runner.Runner) Device {
+	device := Device{Arch: runtime.GOARCH}
+
+	// hw.optional.arm64 is 1 on Apple silicon, even for a translated process.
+	out, err := runner.Query(r, "sysctl", "-n", "hw.optional.arm64")
+	if err == nil && strings.TrimSpace(string(out)) == "1" {
+		device.Arch = "arm64"
+	}
+
+	out, err = runner.Query(r, "sw_vers", "-productVersion")
+	if err == nil {
+		device.OSVersion = strings.TrimSpace(string(out))
+	}
+
+	out, err = runner.Query(r, "sysctl", "-n", "hw.model")
+	if err == nil {
+		device.Model = strings.TrimSpace(string(out))
+	}
+
+	return device
+}
+
+// String returns a string representation of Device.
+func (d Device) String() string {
+	return fmt.Sprintf("Arch='%s'|macOS='%s'|Model='%s'", d.Arch, d.OSVersion, d.Model)
+}
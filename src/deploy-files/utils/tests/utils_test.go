@@ -7,6 +7,7 @@ import (
 	"strings"
 	"testing"
 
+	"github.com/bobllor/macdeploy/src/deploy-files/runner"
 	"github.com/bobllor/macdeploy/src/deploy-files/utils"
 	"github.com/bobllor/macdeploy/src/tests"
 )
@@ -171,3 +172,101 @@ func TestFormatImportantString(t *testing.T) {
 		baseLineIndex += 1
 	}
 }
+
+func TestGetSerialTag(t *testing.T) {
+	fake := runner.NewFake()
+	fake.On("ioreg", `    |   "IOPlatformSerialNumber" = "C02ABC123"`+"\n")
+
+	serialTag, err := utils.GetSerialTag(fake)
+	tests.Checkf(t, err != nil, "failed to get serial tag: %v", err)
+	tests.Checkf(t, serialTag != "C02ABC123", "got serial tag %s", serialTag)
+
+	fake = runner.NewFake()
+	fake.Respond(runner.Response{Match: "ioreg", ExitCode: 1})
+
+	_, err = utils.GetSerialTag(fake)
+	tests.Checkf(t, err == nil, "expected an error for a failed command")
+}
+
+func TestCompareVersions(t *testing.T) {
+	cases := []struct {
+		a        string
+		b        string
+		expected int
+	}{
+		{"1.2.3", "1.2.3", 0},
+		{"1.2", "1.2.0", 0},
+		{"v2.0", "2.0", 0},
+		{"1.10", "1.9", 1},
+		{"119.0.6045.199", "120.0.6099.109", -1},
+		{"2.0", "10.0", -1},
+		{"2.0b3", "2.0", -1},
+		{"2.0b3", "2.0b10", -1},
+		{"2.0-rc1", "2.0-beta2", 1},
+		{"1.2.3 (456)", "1.2.3", 1},
+		{"1.2.3 (456)", "1.2.3 (457)", -1},
+		{"1.2.3+5", "1.2.3-rc1", 1},
+		{"1.2.3.1", "1.2.3 (999)", 1},
+		{"unknown", "0.1", -1},
+		{"", "", 0},
+	}
+
+	for _, c := range cases {
+		got := utils.CompareVersions(c.a, c.b)
+		tests.Checkf(t, got != c.expected, "CompareVersions(%q, %q) = %d, expected %d", c.a, c.b, got, c.expected)
+
+		reverse := utils.CompareVersions(c.b, c.a)
+		tests.Checkf(t, reverse != -c.expected, "CompareVersions(%q, %q) = %d, expected %d", c.b, c.a, reverse, -c.expected)
+	}
+}
+
+func TestIsVersion(t *testing.T) {
+	for _, version := range []string{"1", "1.2.3", "v4.5", "2.0b3", "1.2.3 (456)"} {
+		tests.Checkf(t, !utils.IsVersion(version), "expected %q to be a version", version)
+	}
+
+	for _, version := range []string{"", "latest", "beta 1", "."} {
+		tests.Checkf(t, utils.IsVersion(version), "expected %q to not be a version", version)
+	}
+}
+
+func TestWithinMaxVersion(t *testing.T) {
+	cases := []struct {
+		version string
+		max     string
+		within  bool
+	}{
+		{"14.5.1", "14", true},
+		{"14.5.1", "14.5", true},
+		{"14.5", "14.4", false},
+		{"15.0", "14", false},
+		{"13.6.7", "14.0", true},
+		{"14.0", "14.0", true},
+		{"14.1b2", "14.0", false},
+		{"", "14", false},
+		{"14.0", "latest", false},
+	}
+
+	for _, c := range cases {
+		within := utils.WithinMaxVersion(c.version, c.max)
+		tests.Checkf(t, within != c.within, "WithinMaxVersion(%q, %q) = %t, expected %t", c.version, c.max, within, c.within)
+	}
+}
+
+func TestGetDevice(t *testing.T) {
+	fake := runner.NewFake()
+	fake.On("sysctl -n hw.optional.arm64", "1\n")
+	fake.On("sw_vers -productVersion", "14.5\n")
+	fake.On("sysctl -n hw.model", "Mac14,2\n")
+
+	device := utils.GetDevice(fake)
+	expected := utils.Device{Arch: "arm64", OSVersion: "14.5", Model: "Mac14,2"}
+	tests.Checkf(t, device != expected, "got device %s, expected %s", device, expected)
+
+	fake = runner.NewFake()
+	fake.Respond(runner.Response{Match: "sysctl", ExitCode: 1})
+	fake.Respond(runner.Response{Match: "sw_vers", ExitCode: 1})
+
+	device = utils.GetDevice(fake)
+	tests.Checkf(t, device.OSVersion != "" || device.Model != "", "expected unknown facts, got %s", device)
+}
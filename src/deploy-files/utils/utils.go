@@ -4,9 +4,10 @@ import (
 	"errors"
 	"fmt"
 	"os"
-	"os/exec"
 	"strings"
 	"unicode"
+
+	"github.com/bobllor/macdeploy/src/deploy-files/runner"
 )
 
 // GetFiles reads the directory and returns a map of the files.
@@ -78,14 +79,25 @@ func FormatUsername(value string) string {
 // GetSerialTag retrieves the serial tag for the device.
 //
 // An error will return if the serial tag cannot be retrieved.
-func GetSerialTag() (string, error) {
-	cmd := "ioreg -l | grep IOPlatformSerialNumber"
-	out, err := exec.Command("bash", "-c", cmd).Output()
+func GetSerialTag(r runner.Runner) (string, error) {
+	out, err := runner.Query(r, "ioreg", "-rd1", "-c", "IOPlatformExpertDevice")
 	if err != nil {
 		return "", errors.New("cannot find serial tag of the device")
 	}
 
-	serialTagArr := strings.Split(string(out), "\"")
+	line := ""
+	for outLine := range strings.Lines(string(out)) {
+		if strings.Contains(outLine, "IOPlatformSerialNumber") {
+			line = outLine
+			break
+		}
+	}
+
+	serialTagArr := strings.Split(line, "\"")
+	if len(serialTagArr) < 2 {
+		return "", errors.New("cannot find serial tag of the device")
+	}
+
 	serialTag := serialTagArr[len(serialTagArr)-2]
 
 	return serialTag, nil
@@ -0,0 +1,212 @@
+package utils
+
+import (
+	"cmp"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// version is a parsed version string, see parseVersion.
+type version struct {
+	// numbers are the dotted numbers of the version, e.g. [1 2 3] for 1.2.3.
+	numbers []int
+	// suffix are the parts of the version after the numbers, split into
+	// runs of digits and letters.
+	suffix []string
+	// preRelease is true if the suffix starts with a letter, such as 1.2b3 or 1.2-beta.
+	preRelease bool
+}
+
+// parseVersion parses the version string. The version starts with dotted numbers, anything
+// after the numbers is the suffix: a pre-release if it starts with a letter (2.0b3, 2.0-rc1),
+// otherwise a build (2.0 (1234), 2.0+5, 2.0-1234).
+//
+// False is returned if the version does not start with a number.
+func parseVersion(value string) (version, bool) {
+	value = strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(value), "v"))
+
+	v := version{numbers: make([]int, 0), suffix: make([]string, 0)}
+
+	end := 0
+	for end < len(value) {
+		start := end
+		for end < len(value) && value[end] >= '0' && value[end] <= '9' {
+			end++
+		}
+		if start == end {
+			break
+		}
+
+		number, err := strconv.Atoi(value[start:end])
+		if err != nil {
+			return v, false
+		}
+		v.numbers = append(v.numbers, number)
+
+		// a dot is only a part of the numbers if a number follows it.
+		if end+1 < len(value) && value[end] == '.' && unicode.IsDigit(rune(value[end+1])) {
+			end++
+			continue
+		}
+
+		break
+	}
+
+	if len(v.numbers) == 0 {
+		return v, false
+	}
+
+	rest := strings.TrimLeft(value[end:], " .-+_()")
+	v.suffix = splitVersionSuffix(rest)
+	v.preRelease = len(v.suffix) > 0 && !unicode.IsDigit(rune(v.suffix[0][0]))
+
+	return v, true
+}
+
+// splitVersionSuffix splits the suffix into runs of digits and letters, other characters
+// are separators. The letters are lowercase.
+func splitVersionSuffix(suffix string) []string {
+	parts := make([]string, 0)
+	current := strings.Builder{}
+	currentDigit := false
+
+	flush := func() {
+		if current.Len() > 0 {
+			parts = append(parts, current.String())
+			current.Reset()
+		}
+	}
+
+	for _, r := range strings.ToLower(suffix) {
+		isDigit := unicode.IsDigit(r)
+		if !isDigit && !unicode.IsLetter(r) {
+			flush()
+			continue
+		}
+
+		if current.Len() > 0 && isDigit != currentDigit {
+			flush()
+		}
+
+		current.WriteRune(r)
+		currentDigit = isDigit
+	}
+	flush()
+
+	return parts
+}
+
+// IsVersion returns true if the value can be compared with CompareVersions.
+func IsVersion(value string) bool {
+	_, ok := parseVersion(value)
+
+	return ok
+}
+
+// CompareVersions compares two versions, it returns -1 if a is older than b, 0 if they
+// are equal, and 1 if a is newer than b.
+//
+// The dotted numbers are compared first, missing numbers are zero (1.2 equals 1.2.0).
+// If the numbers are equal, a pre-release (2.0b3, 2.0-rc1) is older than the version
+// without a suffix, and a build (2.0 (1234), 2.0+5) is newer. The suffixes of the same
+// kind are compared part by part, the digits as numbers.
+//
+// A value that is not a version is older than any version.
+func CompareVersions(a string, b string) int {
+	va, okA := parseVersion(a)
+	vb, okB := parseVersion(b)
+	if !okA || !okB {
+		return cmp.Compare(boolRank(okA), boolRank(okB))
+	}
+
+	return compareVersions(va, vb)
+}
+
+// WithinMaxVersion returns true if the version is not newer than the maximum version. Only
+// the numbers given in the maximum are compared, 14.5.1 is within 14 and 14.5 but not 14.4.
+//
+// False is returned if either value is not a version.
+func WithinMaxVersion(value string, maxVersion string) bool {
+	v, okV := parseVersion(value)
+	vMax, okMax := parseVersion(maxVersion)
+	if !okV || !okMax {
+		return false
+	}
+
+	if len(v.numbers) > len(vMax.numbers) {
+		v.numbers = v.numbers[:len(vMax.numbers)]
+		v.suffix = []string{}
+		v.preRelease = false
+	}
+
+	return compareVersions(v, vMax) <= 0
+}
+
+// compareVersions compares two parsed versions, see CompareVersions.
+func compareVersions(va version, vb version) int {
+	for i := range max(len(va.numbers), len(vb.numbers)) {
+		na, nb := 0, 0
+		if i < len(va.numbers) {
+			na = va.numbers[i]
+		}
+		if i < len(vb.numbers) {
+			nb = vb.numbers[i]
+		}
+
+		if c := cmp.Compare(na, nb); c != 0 {
+			return c
+		}
+	}
+
+	if c := cmp.Compare(suffixRank(va), suffixRank(vb)); c != 0 {
+		return c
+	}
+
+	for i := range min(len(va.suffix), len(vb.suffix)) {
+		if c := compareVersionPart(va.suffix[i], vb.suffix[i]); c != 0 {
+			return c
+		}
+	}
+
+	return cmp.Compare(len(va.suffix), len(vb.suffix))
+}
+
+// suffixRank orders the versions with equal numbers: pre-release, no suffix, then build.
+func suffixRank(v version) int {
+	if len(v.suffix) == 0 {
+		return 1
+	}
+	if v.preRelease {
+		return 0
+	}
+
+	return 2
+}
+
+// compareVersionPart compares two parts of a suffix, the digits are compared as numbers
+// and are newer than letters.
+func compareVersionPart(a string, b string) int {
+	na, errA := strconv.Atoi(a)
+	nb, errB := strconv.Atoi(b)
+
+	switch {
+	case errA == nil && errB == nil:
+		return cmp.Compare(na, nb)
+	case errA == nil:
+		return 1
+	case errB == nil:
+		return -1
+	}
+
+	return cmp.Compare(a, b)
+}
+
+// boolRank returns 1 for true and 0 for false.
+func boolRank(b bool) int {
+	if b {
+		return 1
+	}
+
+	return 0
+}
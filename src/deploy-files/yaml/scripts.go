@@ -0,0 +1,135 @@
+package yaml
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// runAsRoot is the run_as value used to run a script as root.
+const runAsRoot string = "root"
+
+// Script is a script entry of the config. The entry is either the file name of the
+// script, or a mapping with the file name and the options of the script.
+type Script struct {
+	// Name is the file name of the script in the dist directory. For an inline script
+	// it is only used for logging and the report.
+	Name string `yaml:"name"`
+
+	// Inline is the body of the script, it is ran instead of a script file.
+	Inline string `yaml:"inline"`
+
+	// Args are the arguments given to the script. If empty, the arguments from
+	// the 'args' field of the scripts are used.
+	Args []string `yaml:"args"`
+
+	// Timeout is the time limit of the script, it is used instead of the command
+	// time limit. Zero uses the command time limit.
+	Timeout time.Duration `yaml:"timeout"`
+
+	// RunAs is the user that runs the script, either root or an account created by
+	// the deployment. If empty, the script runs as the user running the binary.
+	RunAs string `yaml:"run_as"`
+
+	// Required aborts the deployment if the script fails. By default a failed script
+	// is logged and the deployment continues.
+	Required bool `yaml:"required"`
+
+	// ExitCodes are the exit codes of a successful run. By default only 0 is successful.
+	ExitCodes []int `yaml:"exit_codes"`
+}
+
+// UnmarshalYAML reads the script from a file name or a mapping.
+func (s *Script) UnmarshalYAML(unmarshal func(any) error) error {
+	var name string
+	if err := unmarshal(&name); err == nil {
+		*s = Script{Name: name}
+		return nil
+	}
+
+	// the type has no methods, it prevents UnmarshalYAML from being called again.
+	type script Script
+	var v script
+
+	err := unmarshal(&v)
+	if err != nil {
+		return err
+	}
+
+	*s = Script(v)
+
+	return nil
+}
+
+// MarshalYAML writes the script as its file name if it has no options.
+func (s Script) MarshalYAML() (any, error) {
+	if s.Inline == "" && len(s.Args) == 0 && s.Timeout == 0 && s.RunAs == "" && !s.Required && len(s.ExitCodes) == 0 {
+		return s.Name, nil
+	}
+
+	type script Script
+
+	return script(s), nil
+}
+
+// IsInline returns true if the script body is given in the config.
+func (s Script) IsInline() bool {
+	return strings.TrimSpace(s.Inline) != ""
+}
+
+// RunAsRoot returns true if the script runs as root.
+func (s Script) RunAsRoot() bool {
+	return s.RunAs == runAsRoot
+}
+
+// Hooks returns the scripts of every hook point by the field name.
+func (s ScriptTypes) Hooks() map[string][]Script {
+	return map[string][]Script{
+		"pre":            s.Pre,
+		"mid":            s.Mid,
+		"post":           s.Post,
+		"post_accounts":  s.PostAccounts,
+		"pre_filevault":  s.PreFileVault,
+		"post_filevault": s.PostFileVault,
+		"pre_firewall":   s.PreFirewall,
+		"on_failure":     s.OnFailure,
+		"on_cleanup":     s.OnCleanup,
+	}
+}
+
+// ScriptNames returns the file names of the scripts.
+func ScriptNames(scripts []Script) []string {
+	names := make([]string, 0, len(scripts))
+	for _, script := range scripts {
+		names = append(names, script.Name)
+	}
+
+	return names
+}
+
+// validateScripts validates the script entries of a field. The file name is required,
+// the timeout cannot be negative, and the exit codes must be between 0 and 255.
+//
+// It returns a slice of error strings for every failed script.
+func validateScripts(field string, scripts []Script) []string {
+	errs := []string{}
+
+	for i, script := range scripts {
+		prefix := fmt.Sprintf("field '%s' entry %d is invalid", field, i)
+
+		if strings.TrimSpace(script.Name) == "" {
+			errs = append(errs, fmt.Sprintf("%s, 'name' is required", prefix))
+		}
+		if script.Timeout < 0 {
+			errs = append(errs, fmt.Sprintf("%s, 'timeout' (%s) cannot be negative", prefix, script.Timeout))
+		}
+
+		for _, code := range script.ExitCodes {
+			if code < 0 || code > 255 {
+				errs = append(errs, fmt.Sprintf("%s, exit code %d is not between 0 and 255", prefix, code))
+			}
+		}
+	}
+
+	return errs
+}
@@ -0,0 +1,83 @@
+package yaml
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bobllor/assert"
+	"github.com/bobllor/macdeploy/src/tests"
+)
+
+func TestScriptEntries(t *testing.T) {
+	fake, err := getEditableConfig()
+	tests.Checkf(t, err != nil, "failed to read config: %v", err)
+
+	fake["scripts"] = map[string]any{
+		"pre": []any{
+			"plain.sh",
+			map[string]any{
+				"name":       "options.sh",
+				"args":       []string{"--force"},
+				"timeout":    "5m",
+				"run_as":     "root",
+				"required":   true,
+				"exit_codes": []int{0, 2},
+			},
+			map[string]any{
+				"name":   "set_default",
+				"inline": "defaults write com.example key -bool true\n",
+			},
+		},
+	}
+
+	buf, err := Marshal(fake)
+	tests.Checkf(t, err != nil, "failed to marshal config: %v", err)
+
+	config, err := NewConfig(buf)
+	tests.Checkf(t, err != nil, "failed to create new Config: %v", err)
+
+	assert.Nil(t, Validate(config))
+	assert.Equal(t, strings.Join(ScriptNames(config.Scripts.Pre), ","), "plain.sh,options.sh,set_default")
+
+	plain := config.Scripts.Pre[0]
+	assert.Equal(t, plain.Required, false)
+	assert.Equal(t, plain.RunAs, "")
+
+	options := config.Scripts.Pre[1]
+	assert.Equal(t, strings.Join(options.Args, ","), "--force")
+	assert.Equal(t, options.Timeout, 5*time.Minute)
+	assert.Equal(t, options.RunAsRoot(), true)
+	assert.Equal(t, options.Required, true)
+	assert.Equal(t, len(options.ExitCodes), 2)
+	assert.Equal(t, options.IsInline(), false)
+
+	inline := config.Scripts.Pre[2]
+	assert.Equal(t, inline.IsInline(), true)
+	assert.Equal(t, inline.Inline, "defaults write com.example key -bool true\n")
+
+	t.Run("Marshal", func(t *testing.T) {
+		buf, err := Marshal(config.Scripts)
+		tests.Checkf(t, err != nil, "failed to marshal scripts: %v", err)
+
+		assert.Equal(t, strings.Contains(string(buf), "- plain.sh"), true)
+		assert.Equal(t, strings.Contains(string(buf), "name: options.sh"), true)
+	})
+}
+
+func TestValidateScripts(t *testing.T) {
+	config := getConfig()
+
+	config.Scripts.Post = []Script{
+		{Name: ""},
+		{Name: "negative.sh", Timeout: -time.Second},
+		{Name: "codes.sh", ExitCodes: []int{0, 256}},
+	}
+
+	err := Validate(config)
+	assert.NotNil(t, err)
+
+	errs := strings.Split(err.Error(), "\n")
+	assert.Equal(t, len(errs), 3)
+	assert.Equal(t, errs[0], "field 'scripts.post' entry 0 is invalid, 'name' is required")
+}
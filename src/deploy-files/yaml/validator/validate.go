@@ -10,9 +10,22 @@ import (
 )
 
 // main is used to read a config file from a given path and validate it.
+// If no path is given, then the embedded config is validated.
+// Every profile of the config is validated with the base config.
 // It will exit 1 if validation fails, or 0 if it succeeds.
 func main() {
-	config, err := yaml.NewConfig(embedhandler.YAMLBytes)
+	data := embedhandler.YAMLBytes
+	if len(os.Args) > 1 {
+		var err error
+
+		data, err = os.ReadFile(os.Args[1])
+		if err != nil {
+			fmt.Printf("Failed to read config file: %v\n", err)
+			os.Exit(1)
+		}
+	}
+
+	config, err := yaml.NewConfig(data)
 	if err != nil {
 		fmt.Printf("Failed to read config file: %v\n", err)
 		os.Exit(1)
@@ -24,4 +37,25 @@ func main() {
 		fmt.Println(err)
 		os.Exit(1)
 	}
+
+	for _, profile := range config.ProfileNames() {
+		data, err := yaml.ApplyProfile(data, profile)
+		if err != nil {
+			fmt.Printf("Failed to apply profile %s: %v\n", profile, err)
+			os.Exit(1)
+		}
+
+		profileConfig, err := yaml.NewConfig(data)
+		if err != nil {
+			fmt.Printf("Failed to read config file with profile %s: %v\n", profile, err)
+			os.Exit(1)
+		}
+
+		err = yaml.Validate(profileConfig)
+		if err != nil {
+			fmt.Printf("Config file failed to validate with profile %s:\n", profile)
+			fmt.Println(err)
+			os.Exit(1)
+		}
+	}
 }
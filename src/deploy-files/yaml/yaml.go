@@ -4,15 +4,21 @@ import (
 	"bufio"
 	"errors"
 	"fmt"
+	"maps"
 	"os"
-	"os/exec"
 	"os/signal"
+	"slices"
 	"strings"
 	"syscall"
+	"time"
 
 	"github.com/go-playground/validator/v10"
 	"github.com/goccy/go-yaml"
 	"golang.org/x/term"
+
+	"github.com/bobllor/macdeploy/src/deploy-files/checksum"
+	"github.com/bobllor/macdeploy/src/deploy-files/prompt"
+	"github.com/bobllor/macdeploy/src/deploy-files/runner"
 )
 
 type Config struct {
@@ -20,9 +26,14 @@ type Config struct {
 	Accounts map[string]UserInfo `yaml:"accounts"`
 
 	// Packages are the package file names that are to be installed, with
-	// a slice of the install file names used to conditionally
-	// install packages if found in an install directory.
-	Packages map[string][]string `yaml:"packages"`
+	// the installed file names or the bundle identifier used to conditionally
+	// install packages if they are not found in an install directory.
+	Packages map[string]Package `yaml:"packages"`
+
+	// Checksums are the SHA-256 checksums of the packages, DMGs, apps and scripts
+	// of the dist directory by the file name. A file with a checksum is verified
+	// before it is used, files without a checksum are not verified.
+	Checksums map[string]string `yaml:"checksums"`
 
 	// InstallDirectories is a slice of paths that will contain the install files
 	// of packages.
@@ -31,6 +42,10 @@ type Config struct {
 	// Scripts is a type used to inject script execution during deployment.
 	Scripts ScriptTypes `yaml:"scripts"`
 
+	// Stages is a slice of StageConfig used to reorder, disable, or add stages
+	// of the deployment process. If empty, the default stages are used.
+	Stages []StageConfig `yaml:"stages"`
+
 	// Admin is a UserInfo type that is used for admin elevation. For security purposes
 	// this can be omitted.
 	Admin UserInfo
@@ -63,6 +78,13 @@ type Config struct {
 	// The option "force" will not override the confirmation if either
 	// the FileVault process or the POST to the server with the FileVault key failed.
 	Cleanup string `yaml:"cleanup" validate:"oneof=warn force"`
+
+	// Timeouts are the time limits of the commands and stages of the deployment.
+	Timeouts Timeouts `yaml:"timeouts"`
+
+	// Profiles are named overlays of the config, used to deploy different device types
+	// with the same binary. The profile is applied with ApplyProfile.
+	Profiles map[string]any `yaml:"profiles"`
 }
 
 type UserInfo struct {
@@ -75,11 +97,62 @@ type UserInfo struct {
 // ScriptTypes contains fields with string slices representing the
 // script file names used to execute during the lifecycle of the process.
 type ScriptTypes struct {
-	Pre  []string `yaml:"pre"`
-	Mid  []string `yaml:"mid"`
-	Post []string `yaml:"post"`
+	Pre  []Script `yaml:"pre"`
+	Mid  []Script `yaml:"mid"`
+	Post []Script `yaml:"post"`
+
+	// PostAccounts are ran after the accounts stage completes.
+	PostAccounts []Script `yaml:"post_accounts"`
+	// PreFileVault are ran before the FileVault stage starts.
+	PreFileVault []Script `yaml:"pre_filevault"`
+	// PostFileVault are ran after the FileVault stage completes.
+	PostFileVault []Script `yaml:"post_filevault"`
+	// PreFirewall are ran before the Firewall stage starts.
+	PreFirewall []Script `yaml:"pre_firewall"`
+	// OnFailure are ran after each stage that failed or timed out.
+	OnFailure []Script `yaml:"on_failure"`
+	// OnCleanup are ran before the deployment files are removed with --cleanup.
+	OnCleanup []Script `yaml:"on_cleanup"`
+
+	// Args are the arguments given to the scripts by the script file name.
+	// This applies to the scripts of all stages.
+	Args map[string][]string `yaml:"args"`
+}
+
+// Timeouts are the time limits of the deployment. A zero duration is no limit.
+type Timeouts struct {
+	// Command is the time limit of each command, such as an installer or a script.
+	// By default the value is 1 hour.
+	Command time.Duration `yaml:"command"`
+
+	// Stages are the time limits of the stages by the stage name. By default
+	// the stages have no limit.
+	Stages map[string]time.Duration `yaml:"stages"`
+
+	// Watchdog is the interval the running stage and commands are logged at.
+	// By default the value is 1 minute.
+	Watchdog time.Duration `yaml:"watchdog"`
+}
+
+// StageConfig is the configuration of a stage in the deployment process.
+type StageConfig struct {
+	// Name is the name of a default stage or a new stage.
+	Name string `yaml:"name"`
+
+	// DependsOn are the names of the stages that must run before this stage.
+	DependsOn []string `yaml:"depends_on"`
+
+	// Disabled prevents the stage from running.
+	Disabled bool `yaml:"disabled"`
+
+	// Scripts are the script files executed by a new stage. This is ignored
+	// for the default stages.
+	Scripts []Script `yaml:"scripts"`
 }
 
+// dryRunPassword is the password used in place of a password prompt during a dry run.
+const dryRunPassword string = "<password>"
+
 // NewConfig returns a struct containing data read from the YAML file. The file is read
 // through embedding.
 //
@@ -87,6 +160,10 @@ type ScriptTypes struct {
 func NewConfig(data []byte) (*Config, error) {
 	config := Config{
 		Cleanup: "warn",
+		Timeouts: Timeouts{
+			Command:  time.Hour,
+			Watchdog: time.Minute,
+		},
 	}
 
 	err := yaml.Unmarshal(data, &config)
@@ -114,13 +191,12 @@ func Validate(config *Config) error {
 	yamlErrHandler.SetKeyError("Cleanup", "field 'cleanup' (%s) is invalid, validation failed on %s (allowed values [%s])")
 	yamlErrHandler.SetKeyError("ServerHost", "field 'server_host' (%s) is invalid, validation failed on %s (https/http)")
 
+	errBuilder := []string{}
+
 	err := validate.Struct(config)
 	if err != nil {
-
 		errs := err.(validator.ValidationErrors)
 
-		errBuilder := []string{}
-
 		for _, e := range errs {
 			errStr, configErr := yamlErrHandler.GetKeyError(e.Field())
 			if configErr != nil {
@@ -139,13 +215,99 @@ func Validate(config *Config) error {
 
 			errBuilder = append(errBuilder, outErr)
 		}
+	}
 
+	hooks := config.Scripts.Hooks()
+	for _, field := range slices.Sorted(maps.Keys(hooks)) {
+		errBuilder = append(errBuilder, validateScripts("scripts."+field, hooks[field])...)
+	}
+	errBuilder = append(errBuilder, validateStages(config.Stages)...)
+	errBuilder = append(errBuilder, validateTimeouts(config.Timeouts)...)
+	errBuilder = append(errBuilder, validateChecksums(config.Checksums)...)
+	errBuilder = append(errBuilder, validatePackages(config.Packages)...)
+
+	if len(errBuilder) > 0 {
 		return errors.New(strings.Join(errBuilder, "\n"))
 	}
 
 	return nil
 }
 
+// validateStages validates the stage names of the config. The stage names
+// must not be empty and must be unique.
+//
+// It returns a slice of error strings for every failed stage.
+func validateStages(stages []StageConfig) []string {
+	errs := []string{}
+	seen := map[string]struct{}{}
+
+	for i, stage := range stages {
+		name := strings.TrimSpace(stage.Name)
+		if name == "" {
+			errs = append(errs, fmt.Sprintf("field 'stages' entry %d is invalid, 'name' is required", i))
+			continue
+		}
+
+		if _, ok := seen[name]; ok {
+			errs = append(errs, fmt.Sprintf("field 'stages' entry %d is invalid, stage '%s' is duplicated", i, name))
+			continue
+		}
+
+		seen[name] = struct{}{}
+
+		errs = append(errs, validateScripts(fmt.Sprintf("stages.%s.scripts", name), stage.Scripts)...)
+	}
+
+	return errs
+}
+
+// validateTimeouts validates the durations of the timeouts, they cannot be negative.
+// The stage names are validated with the stages of the deployment.
+//
+// It returns a slice of error strings for every failed timeout.
+func validateTimeouts(timeouts Timeouts) []string {
+	errs := []string{}
+
+	if timeouts.Command < 0 {
+		errs = append(errs, fmt.Sprintf("field 'timeouts.command' (%s) is invalid, it cannot be negative", timeouts.Command))
+	}
+	if timeouts.Watchdog < 0 {
+		errs = append(errs, fmt.Sprintf("field 'timeouts.watchdog' (%s) is invalid, it cannot be negative", timeouts.Watchdog))
+	}
+
+	for name, timeout := range timeouts.Stages {
+		if timeout < 0 {
+			errs = append(errs, fmt.Sprintf("field 'timeouts.stages.%s' (%s) is invalid, it cannot be negative", name, timeout))
+		}
+	}
+
+	slices.Sort(errs)
+
+	return errs
+}
+
+// validateChecksums validates the checksums of the files, the values must be
+// hex encoded SHA-256 checksums.
+//
+// It returns a slice of error strings for every failed checksum.
+func validateChecksums(checksums map[string]string) []string {
+	errs := []string{}
+
+	for _, name := range slices.Sorted(maps.Keys(checksums)) {
+		if strings.TrimSpace(name) == "" {
+			errs = append(errs, "field 'checksums' is invalid, the file name cannot be empty")
+			continue
+		}
+
+		value := strings.TrimSpace(checksums[name])
+		if !checksum.IsValid(value) {
+			errs = append(errs, fmt.Sprintf("field 'checksums.%s' (%s) is invalid, it must be a SHA-256 checksum", name, value))
+		}
+	}
+
+	return errs
+}
+
 // Marshal serializes an interface into a bytes value.
 func Marshal(v any) ([]byte, error) {
 	return yaml.Marshal(v)
@@ -167,13 +329,13 @@ func Unmarshal(data []byte) (any, error) {
 // This uses a command execution with whoami.
 //
 // It returns an error if the command fails to run.
-func (u *UserInfo) SetUsername() error {
+func (u *UserInfo) SetUsername(r runner.Runner) error {
 	// prevents accidental runs
 	if u.Username != "" {
 		return nil
 	}
 
-	out, err := exec.Command("whoami").Output()
+	out, err := runner.Query(r, "whoami")
 	if err != nil {
 		return err
 	}
@@ -187,6 +349,11 @@ func (u *UserInfo) SetUsername() error {
 // SetUsernameManual is used to set the username manually from
 // user input.
 func (u *UserInfo) SetUsernameManual() error {
+	err := prompt.Check("username")
+	if err != nil {
+		return err
+	}
+
 	scanner := bufio.NewReader(os.Stdin)
 	fmt.Print("Enter username: ")
 	username, err := scanner.ReadString('\n')
@@ -204,9 +371,22 @@ func (u *UserInfo) SetUsernameManual() error {
 //
 // If confirmation is given, then it will prompt a re-entry of the original password.
 //
+// No input is read if the Runner is in a dry run.
+//
 // It returns an error if the maximum attempt is reached or if an error occurs.
 // By default the maximum attempts is 3.
-func (u *UserInfo) SetPassword(confirmPassword bool) error {
+func (u *UserInfo) SetPassword(r runner.Runner, confirmPassword bool) error {
+	// no input is read during a dry run.
+	if r.DryRun() {
+		u.Password = dryRunPassword
+		return nil
+	}
+
+	err := prompt.Check(u.describe("password"))
+	if err != nil {
+		return err
+	}
+
 	fmt.Print("Enter password: ")
 	pwOne, err := u.readPassword()
 	if err != nil {
@@ -245,6 +425,27 @@ func (u *UserInfo) SetPassword(confirmPassword bool) error {
 	return nil
 }
 
+// SetFromEnv sets the empty username and password from the environment variables.
+// Empty environment variable names are ignored.
+func (u *UserInfo) SetFromEnv(usernameEnv string, passwordEnv string) {
+	if u.Username == "" && usernameEnv != "" {
+		u.Username = os.Getenv(usernameEnv)
+	}
+
+	if u.Password == "" && passwordEnv != "" {
+		u.Password = os.Getenv(passwordEnv)
+	}
+}
+
+// describe returns the name of a value of the user, used for messages.
+func (u *UserInfo) describe(value string) string {
+	if u.Username == "" {
+		return value
+	}
+
+	return fmt.Sprintf("%s for %s", value, u.Username)
+}
+
 // readPassword reads the input from STDIN securely.
 func (u *UserInfo) readPassword() (string, error) {
 	stdin := int(syscall.Stdin)
@@ -257,6 +458,11 @@ func (u *UserInfo) readPassword() (string, error) {
 
 	ch := make(chan os.Signal, 1)
 	signal.Notify(ch, os.Interrupt)
+	// the interrupts are handled by the deployment after the prompt.
+	defer func() {
+		signal.Stop(ch)
+		close(ch)
+	}()
 	go func() {
 		for _ = range ch {
 			term.Restore(stdin, oldState)
@@ -277,9 +483,11 @@ func (u *UserInfo) readPassword() (string, error) {
 
 // InitializeSudo starts a sudo session without the need of manual input.
 // This can be called multiple times to refresh the sudo timer.
-func (u *UserInfo) InitializeSudo() error {
-	initSudoCmd := fmt.Sprintf("sudo -S echo <<< '%s'", u.Password)
-	err := exec.Command("bash", "-c", initSudoCmd).Run()
+func (u *UserInfo) InitializeSudo(r runner.Runner) error {
+	cmd := runner.NewCmd("sudo", "-S", "-v")
+	cmd.Stdin = u.Password + "\n"
+
+	_, err := r.Exec(cmd)
 
 	if err != nil {
 		return err
@@ -288,9 +496,20 @@ func (u *UserInfo) InitializeSudo() error {
 	return nil
 }
 
+// VerifySudo checks the password of the user with sudo. The cached sudo timestamp
+// is ignored and is not changed.
+func (u *UserInfo) VerifySudo(r runner.Runner) error {
+	cmd := runner.NewCmd("sudo", "-k", "-S", "-v")
+	cmd.Stdin = u.Password + "\n"
+
+	_, err := r.Exec(cmd)
+
+	return err
+}
+
 // ResetSudo removes the sudo timestamp, resetting the permissions.
-func (u *UserInfo) ResetSudo() error {
-	err := exec.Command("bash", "-c", "sudo -K").Run()
+func (u *UserInfo) ResetSudo(r runner.Runner) error {
+	err := runner.Run(r, "sudo", "-K")
 	if err != nil {
 		return err
 	}
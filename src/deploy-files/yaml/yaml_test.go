@@ -4,8 +4,10 @@ import (
 	"fmt"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/bobllor/assert"
+	"github.com/bobllor/macdeploy/src/deploy-files/runner"
 	"github.com/bobllor/macdeploy/src/tests"
 )
 
@@ -24,8 +26,8 @@ func getConfig() *Config {
 		},
 	}
 
-	packages := map[string][]string{
-		"some pkg name.pkg": {"installed file"},
+	packages := map[string]Package{
+		"some pkg name.pkg": {Installed: []string{"installed file"}},
 		"pkg_file_no_ext":   {},
 	}
 
@@ -35,9 +37,9 @@ func getConfig() *Config {
 	}
 
 	scripts := ScriptTypes{
-		Pre:  []string{"run-before-process.sh"},
-		Mid:  []string{"run-during-process.sh"},
-		Post: []string{"run-after-process.sh"},
+		Pre:  []Script{{Name: "run-before-process.sh"}},
+		Mid:  []Script{{Name: "run-during-process.sh"}},
+		Post: []Script{{Name: "run-after-process.sh"}},
 	}
 
 	admin := UserInfo{
@@ -231,7 +233,7 @@ func TestEmptyPackages(t *testing.T) {
 		t.Fatalf("Missing key %s", baseString)
 	}
 
-	if len(config.Packages[baseString]) != 0 {
+	if len(config.Packages[baseString].Installed) != 0 {
 		t.Fatalf("Package %s is not 0", baseString)
 	}
 }
@@ -264,3 +266,306 @@ func TestFormatUrlWithSlash(t *testing.T) {
 
 	assert.Equal(t, newUrl, baseUrl)
 }
+
+func TestValidateStages(t *testing.T) {
+	config := getConfig()
+
+	config.Stages = []StageConfig{
+		{Name: "packages"},
+		{Name: "site_setup", Scripts: []Script{{Name: "site.sh"}}, DependsOn: []string{"packages"}},
+	}
+
+	err := Validate(config)
+	tests.Checkf(t, err != nil, "failed to validate stages: %v", err)
+
+	t.Run("Duplicate Stage", func(t *testing.T) {
+		config.Stages = append(config.Stages, StageConfig{Name: "packages"})
+
+		err := Validate(config)
+		tests.Checkf(t, err == nil, "expected error from duplicate stage")
+	})
+
+	t.Run("Missing Name", func(t *testing.T) {
+		config.Stages = []StageConfig{{Disabled: true}}
+
+		err := Validate(config)
+		tests.Checkf(t, err == nil, "expected error from missing stage name")
+	})
+}
+
+func TestTimeouts(t *testing.T) {
+	fake, err := getEditableConfig()
+	tests.Checkf(t, err != nil, "failed to read config: %v", err)
+
+	delete(fake, "timeouts")
+
+	buf, err := Marshal(fake)
+	tests.Checkf(t, err != nil, "failed to marshal config: %v", err)
+	config, err := NewConfig(buf)
+	tests.Checkf(t, err != nil, "failed to create new Config: %v", err)
+
+	assert.Equal(t, config.Timeouts.Command, time.Hour)
+	assert.Equal(t, config.Timeouts.Watchdog, time.Minute)
+
+	fake["timeouts"] = map[string]any{
+		"command": "90s",
+		"stages":  map[string]any{"packages": "2h"},
+	}
+
+	buf, err = Marshal(fake)
+	tests.Checkf(t, err != nil, "failed to marshal config: %v", err)
+	config, err = NewConfig(buf)
+	tests.Checkf(t, err != nil, "failed to create new Config: %v", err)
+
+	assert.Equal(t, config.Timeouts.Command, 90*time.Second)
+	assert.Equal(t, config.Timeouts.Stages["packages"], 2*time.Hour)
+	assert.Nil(t, Validate(config))
+
+	t.Run("Negative Timeout", func(t *testing.T) {
+		config.Timeouts.Stages["packages"] = -time.Second
+
+		err := Validate(config)
+		tests.Checkf(t, err == nil, "expected error from negative timeout")
+	})
+}
+
+func TestValidateChecksums(t *testing.T) {
+	config := getConfig()
+
+	config.Checksums = map[string]string{
+		"package_1.pkg": "98EA6E4F216F2FB4B69FFF9B3A44842C38686CA685F3F55DC48C5D3FB1107BE4",
+		"setup.sh":      "cbc80bb5c0c0f8944bf73b3a429505ac5cde16644978bc9a1e74c5755f8ca556",
+	}
+
+	err := Validate(config)
+	tests.Checkf(t, err != nil, "failed to validate checksums: %v", err)
+
+	t.Run("Invalid Checksum", func(t *testing.T) {
+		config.Checksums["app.dmg"] = "abc123"
+
+		err := Validate(config)
+		tests.Checkf(t, err == nil, "expected error from invalid checksum")
+		assert.Equal(t, strings.Contains(err.Error(), "checksums.app.dmg"), true)
+	})
+}
+
+func TestPackages(t *testing.T) {
+	data := []byte(`
+server_host: "https://127.0.0.1:5000"
+packages:
+  old.pkg:
+    - "old.app"
+  empty.pkg:
+  chrome.pkg:
+    installed:
+      - "google chrome.app"
+    bundle_id: com.google.Chrome
+    min_version: "120.0.6099.109"
+`)
+
+	config, err := NewConfig(data)
+	tests.Checkf(t, err != nil, "failed to create new Config: %v", err)
+	assert.Nil(t, Validate(config))
+
+	assert.Equal(t, strings.Join(config.Packages["old.pkg"].Installed, ","), "old.app")
+	assert.Equal(t, len(config.Packages["empty.pkg"].Installed), 0)
+
+	chrome := config.Packages["chrome.pkg"]
+	assert.Equal(t, strings.Join(chrome.Installed, ","), "google chrome.app")
+	assert.Equal(t, chrome.BundleID, "com.google.Chrome")
+	assert.Equal(t, chrome.MinVersion, "120.0.6099.109")
+
+	buf, err := Marshal(config)
+	tests.Checkf(t, err != nil, "failed to marshal config: %v", err)
+	config, err = NewConfig(buf)
+	tests.Checkf(t, err != nil, "failed to create new Config: %v", err)
+	assert.Equal(t, config.Packages["chrome.pkg"].BundleID, "com.google.Chrome")
+	assert.Equal(t, strings.Join(config.Packages["old.pkg"].Installed, ","), "old.app")
+
+	t.Run("Min Version Without Bundle ID", func(t *testing.T) {
+		config.Packages["old.pkg"] = Package{MinVersion: "1.0"}
+
+		err := Validate(config)
+		tests.Checkf(t, err == nil, "expected error from min_version without bundle_id")
+	})
+
+	t.Run("Invalid Min Version", func(t *testing.T) {
+		config.Packages["old.pkg"] = Package{BundleID: "com.example.old", MinVersion: "latest"}
+
+		err := Validate(config)
+		tests.Checkf(t, err == nil, "expected error from invalid min_version")
+	})
+
+	t.Run("Receipts", func(t *testing.T) {
+		config.Packages["old.pkg"] = Package{Receipts: []string{"com.example.old"}, ReceiptVersion: "2.0"}
+		assert.Nil(t, Validate(config))
+
+		buf, err := Marshal(config)
+		tests.Checkf(t, err != nil, "failed to marshal config: %v", err)
+		config, err := NewConfig(buf)
+		tests.Checkf(t, err != nil, "failed to create new Config: %v", err)
+		assert.Equal(t, strings.Join(config.Packages["old.pkg"].Receipts, ","), "com.example.old")
+		assert.Equal(t, config.Packages["old.pkg"].ReceiptVersion, "2.0")
+	})
+
+	t.Run("Receipt Version Without Receipts", func(t *testing.T) {
+		config.Packages["old.pkg"] = Package{ReceiptVersion: "2.0"}
+
+		err := Validate(config)
+		tests.Checkf(t, err == nil, "expected error from receipt_version without receipts")
+	})
+
+	t.Run("Invalid Receipts", func(t *testing.T) {
+		config.Packages["old.pkg"] = Package{Receipts: []string{" "}, ReceiptVersion: "latest"}
+
+		err := Validate(config)
+		tests.Checkf(t, err == nil, "expected error from invalid receipts")
+	})
+}
+
+func TestSetPolicy(t *testing.T) {
+	fake := runner.NewFake()
+	policies := Policies{MinChars: 8, ChangeOnLogin: true}
+
+	_, err := policies.SetPolicy(fake, policies.BuildCommand(), "john.doe")
+	assert.Nil(t, err)
+
+	expected := "sudo pwpolicy -u john.doe -setpolicy newPasswordRequired=1 minChars=8"
+	assert.Equal(t, strings.Join(fake.Calls()[0].Args, " "), expected[len("sudo "):])
+	assert.Equal(t, len(fake.Calls()[0].Args), 5)
+}
+
+func TestSetUsername(t *testing.T) {
+	fake := runner.NewFake()
+	fake.SetDryRun(true)
+	fake.On("whoami", "admin\n")
+
+	user := UserInfo{}
+	assert.Nil(t, user.SetUsername(fake))
+	assert.Equal(t, user.Username, "admin")
+}
+
+func TestSetPasswordDryRun(t *testing.T) {
+	fake := runner.NewFake()
+	fake.SetDryRun(true)
+
+	user := UserInfo{Username: "admin"}
+	assert.Nil(t, user.SetPassword(fake, true))
+	assert.NotEqual(t, user.Password, "")
+}
+
+func TestInitializeSudo(t *testing.T) {
+	fake := runner.NewFake()
+	user := UserInfo{Username: "admin", Password: `it's a "$pass" word`}
+
+	assert.Nil(t, user.InitializeSudo(fake))
+	assert.Nil(t, user.VerifySudo(fake))
+
+	for _, cmd := range fake.Calls() {
+		assert.Equal(t, cmd.Stdin, user.Password+"\n")
+		assert.Equal(t, strings.Contains(strings.Join(cmd.Args, " "), user.Password), false)
+	}
+}
+
+func TestSortPackages(t *testing.T) {
+	packages := map[string]Package{
+		"agent-config.pkg": {DependsOn: []string{"Agent.pkg"}},
+		"agent.pkg":        {DependsOn: []string{"runtime.pkg"}},
+		"runtime.pkg":      {Order: 5},
+		"browser.pkg":      {},
+		"office.pkg":       {Order: -1},
+		"zoom.pkg":         {DependsOn: []string{"excluded.pkg"}},
+	}
+
+	order, err := SortPackages(packages)
+	assert.Nil(t, err)
+	assert.Equal(t, strings.Join(order, ","), "office.pkg,browser.pkg,zoom.pkg,runtime.pkg,agent.pkg,agent-config.pkg")
+
+	// the order is the same on every call.
+	for range 10 {
+		again, _ := SortPackages(packages)
+		assert.Equal(t, strings.Join(again, ","), strings.Join(order, ","))
+	}
+
+	t.Run("Cycle", func(t *testing.T) {
+		packages := map[string]Package{
+			"a.pkg": {DependsOn: []string{"b.pkg"}},
+			"b.pkg": {DependsOn: []string{"c.pkg"}},
+			"c.pkg": {DependsOn: []string{"a.pkg"}},
+			"d.pkg": {},
+		}
+
+		_, err := SortPackages(packages)
+		tests.Checkf(t, err == nil, "expected error from dependency cycle")
+		assert.Equal(t, err.Error(), "dependency cycle found: a.pkg -> b.pkg -> c.pkg -> a.pkg")
+	})
+
+	t.Run("Self Dependency", func(t *testing.T) {
+		_, err := SortPackages(map[string]Package{"a.pkg": {DependsOn: []string{"a.pkg"}}})
+		tests.Checkf(t, err == nil, "expected error from self dependency")
+	})
+}
+
+func TestValidatePackageDependencies(t *testing.T) {
+	config := getConfig()
+	config.Packages = map[string]Package{
+		"agent.pkg":   {DependsOn: []string{"runtime.pkg"}, Order: 1},
+		"runtime.pkg": {},
+	}
+	assert.Nil(t, Validate(config))
+
+	t.Run("Unknown Dependency", func(t *testing.T) {
+		config.Packages["agent.pkg"] = Package{DependsOn: []string{"missing.pkg"}}
+
+		err := Validate(config)
+		tests.Checkf(t, err == nil, "expected error from unknown dependency")
+	})
+
+	t.Run("Cycle", func(t *testing.T) {
+		config.Packages["agent.pkg"] = Package{DependsOn: []string{"runtime.pkg"}}
+		config.Packages["runtime.pkg"] = Package{DependsOn: []string{"agent.pkg"}}
+
+		err := Validate(config)
+		tests.Checkf(t, err == nil, "expected error from dependency cycle")
+		tests.Checkf(t, !strings.Contains(err.Error(), "dependency cycle"), "expected cycle in error: %v", err)
+	})
+}
+
+func TestValidatePackageConditions(t *testing.T) {
+	data := []byte(`
+server_host: "https://127.0.0.1:5000"
+packages:
+  intel-agent.pkg:
+    arch: amd64
+    max_os: "13"
+  apple-agent.pkg:
+    arch: arm64
+    min_os: "14.0"
+    models: ["MacBookPro*", "Mac14,?"]
+`)
+
+	config, err := NewConfig(data)
+	tests.Checkf(t, err != nil, "failed to create new Config: %v", err)
+	assert.Nil(t, Validate(config))
+	assert.Equal(t, config.Packages["apple-agent.pkg"].HasConditions(), true)
+	assert.Equal(t, strings.Join(config.Packages["apple-agent.pkg"].Models, ","), "MacBookPro*,Mac14,?")
+
+	invalid := []Package{
+		{Arch: "x86_64"},
+		{MinOS: "sonoma"},
+		{MaxOS: "latest"},
+		{MinOS: "14.1", MaxOS: "14.0"},
+		{Models: []string{""}},
+		{Models: []string{"MacBookPro["}},
+	}
+
+	for _, pkg := range invalid {
+		config.Packages["intel-agent.pkg"] = pkg
+
+		err := Validate(config)
+		tests.Checkf(t, err == nil, "expected error from invalid conditions %+v", pkg)
+	}
+
+	config.Packages["intel-agent.pkg"] = Package{MinOS: "14.1", MaxOS: "14"}
+	assert.Nil(t, Validate(config))
+}
@@ -41,6 +41,8 @@ def create_app(config_arg: Config = None, *, root: Path | str = None) -> Flask:
         "log_server_path": root / conf.LOGS_NAME / conf.SERVER_LOGS_NAME ,
         "dist_path": root / conf.DIST_DIR_NAME,
         "keys_path": root / conf.KEYS_NAME,
+        "reports_path": root / conf.REPORTS_NAME,
+        "device_configs_path": root / conf.DEVICE_CONFIGS_NAME,
         "testing": False,
         "token_path": conf.SERVER_PATH / ".token",
         "token_bits": 32,
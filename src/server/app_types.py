@@ -7,6 +7,8 @@ class Config(TypedDict):
     log_path: Path | str
     log_server_path: Path | str
     keys_path: Path | str
+    reports_path: Path | str
+    device_configs_path: Path | str
     token_path: Path | str
     dist_path: Path | str
     testing: bool
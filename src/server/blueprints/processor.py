@@ -53,5 +53,20 @@ class Processor():
                 data: dict[str, Any] = future.result()
             
             return jsonify(data), data["statusCode"]
+
+        @bp.route("/api/report", methods=["POST"])
+        def add_report():
+            '''Adds the deployment report from the client device to the server.'''
+            process: Process = Process(log=self.logger)
+            self.logger.debug(f"Reports API accessed by {request.remote_addr}")
+
+            content: types.ReportInfo = request.get_json()
+
+            with ThreadPoolExecutor(max_workers=2) as executor:
+                future: Future = executor.submit(process.add_report, content, self.config["reports_path"])
+
+                data: dict[str, Any] = future.result()
+
+            return jsonify(data), data["statusCode"]
         
         return bp
\ No newline at end of file
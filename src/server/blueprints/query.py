@@ -6,6 +6,7 @@ from pathlib import Path
 from typing import Any, TypedDict
 from datetime import datetime, timezone
 import system.utils as utils
+import json
 import os
 
 
@@ -95,4 +96,59 @@ class Query():
         
             return res, res["status_code"]
 
+        @bp.get("/api/config/<device>")
+        def get_device_config(device: str):
+            '''Get the config overlay of the device, if it exists.
+            The overlay is read from the JSON file `<device>.json` in the device configs folder,
+            and is returned in the `content` of the response.
+            '''
+            self.logger.info(f"Device config query accessed")
+            configs_path: Path = Path(self.config["device_configs_path"])
+
+            device = device.strip()
+            config_path: Path = configs_path / f"{device}.json"
+
+            content: dict[str, Any] = {}
+            res: dict[str, Any] = utils.generate_response("success", status_code=200, content=content, message="Device config found")
+
+            # the device name is used as a file name, it must not leave the folder.
+            if config_path.parent != configs_path:
+                self.logger.warning(f"Invalid device config query: {device}")
+                res["status"] = "error"
+                res["status_code"] = 400
+                res["message"] = f"Device {device} is invalid"
+
+                return res, res["status_code"]
+
+            if not config_path.exists():
+                self.logger.info(f"Queried device '{device}' does not have a config")
+                res["status"] = "error"
+                res["message"] = f"Device {device} does not have a config"
+
+                return res, res["status_code"]
+
+            try:
+                with open(config_path, "r") as file:
+                    overlay: Any = json.load(file)
+            except (OSError, json.JSONDecodeError) as e:
+                self.logger.error(f"Failed to read device config {config_path}: {e}")
+                res["status"] = "error"
+                res["status_code"] = 500
+                res["message"] = f"Failed to read config of device {device}"
+
+                return res, res["status_code"]
+
+            if not isinstance(overlay, dict):
+                self.logger.error(f"Device config {config_path} is not a JSON object")
+                res["status"] = "error"
+                res["status_code"] = 500
+                res["message"] = f"Config of device {device} is invalid"
+
+                return res, res["status_code"]
+
+            content.update(overlay)
+            self.logger.debug(f"Response: {res}")
+
+            return res, res["status_code"]
+
         return bp
\ No newline at end of file
@@ -9,6 +9,8 @@ X86_BINARY_NAME: str = "x86_64-macdeploy"
 
 # directories
 KEYS_NAME: str= "keys"
+REPORTS_NAME: str = "reports"
+DEVICE_CONFIGS_NAME: str = "device-configs"
 SERVER_NAME: str = "server"
 LOGS_NAME: str = "logs"
 SERVER_LOGS_NAME: str = "server-logs"
@@ -17,6 +19,8 @@ DIST_DIR_NAME: str = "dist"
 
 # directory paths
 KEYS_PATH: Path = ROOT_PATH / KEYS_NAME
+REPORTS_PATH: Path = ROOT_PATH / REPORTS_NAME
+DEVICE_CONFIGS_PATH: Path = ROOT_PATH / DEVICE_CONFIGS_NAME
 
 SERVER_PATH: Path = ROOT_PATH / "src" / SERVER_NAME
 # client files, binaries, are stored in this location
@@ -1,9 +1,11 @@
 from pathlib import Path
-from .system_types import LogInfo, KeyInfo
+from .system_types import LogInfo, KeyInfo, ReportInfo
 from . import utils
 from logger import Log
-from datetime import date
+from datetime import date, datetime
 from typing import Any
+import json
+import uuid
 
 class Process:
     def __init__(self, *, log: Log):
@@ -18,6 +20,7 @@ class Process:
 
         self._log_info_keys: list[str] = [key for key in LogInfo.__annotations__.keys()]
         self._key_info_keys: list[str] = [key for key in KeyInfo.__annotations__.keys()]
+        self._report_info_keys: list[str] = [key for key in ReportInfo.__annotations__.keys()]
 
     def add_filevault(self, key_info: KeyInfo, keys_dir: Path | str) -> dict[str, Any]:
         '''Adds the laptop device and key to the server.
@@ -152,6 +155,66 @@ class Process:
             statusCode=200
         )
     
+    def add_report(self, report_info: ReportInfo, reports_dir: Path | str) -> dict[str, Any]:
+        '''Adds the deployment report from the client device to the server.
+        It returns a dictionary response indicating its status and message.
+
+        The report is stored as a JSON file under a folder of the device's serial tag,
+        with the file named after the time it was received and a unique suffix.
+        The serial tag must be a single path segment, it cannot leave the reports folder.
+        '''
+        reports_path: Path = Path(reports_dir)
+
+        validation_res: dict[str, Any] = self._validate_info(self._report_info_keys, report_info)
+        if validation_res["status"] == "error":
+            self.log.error(f"Missing key, got: {[key for key in report_info]}")
+            return validation_res
+
+        if not isinstance(report_info["report"], dict):
+            self.log.error(f"Invalid report type: {type(report_info['report'])}")
+            return utils.generate_response(
+                status="error",
+                content="Report must be a JSON object",
+                statusCode=400
+            )
+
+        serial: Any = report_info["serialTag"]
+
+        # the serial tag is used as a folder name, it must not leave the reports folder.
+        if not isinstance(serial, str) or serial in ("", ".", "..") or Path(serial).name != serial \
+            or (reports_path / serial).parent != reports_path:
+            self.log.error(f"Invalid serial tag for report: {serial!r}")
+            return utils.generate_response(
+                status="error",
+                content="Serial tag is invalid",
+                statusCode=400
+            )
+
+        # reports received in the same second do not overwrite each other.
+        report_name: str = f"{datetime.now().strftime('%Y-%m-%d_%H%M%S')}_{uuid.uuid4().hex[:8]}.json"
+
+        try:
+            report_file_path: Path = reports_path / serial / report_name
+            report_file_path.parent.mkdir(parents=True, exist_ok=True)
+
+            with open(report_file_path, "x") as file:
+                json.dump(report_info["report"], file, indent=2)
+        except Exception:
+            self.log.exception("Failed to write report to the server")
+
+            return utils.generate_response(
+                status="error",
+                content="An unknown error occurred on the server",
+                statusCode=500
+            )
+
+        self.log.info(f"Added report {report_name} for {serial}")
+
+        return utils.generate_response(
+            content="Successfully added report to server",
+            statusCode=200
+        )
+
     def _validate_info(self, keys_to_check: list[str], info: dict[str, Any]) -> dict[str, Any]:
         '''Checks the info dictionary for validating the responses.'''
         missing_keys: list[str] = []
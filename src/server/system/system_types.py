@@ -1,4 +1,4 @@
-from typing import TypedDict
+from typing import TypedDict, Any
 
 class LogInfo(TypedDict):
     logFileName: str
@@ -6,4 +6,8 @@ class LogInfo(TypedDict):
 
 class KeyInfo(TypedDict):
     key: str
-    serialTag: str
\ No newline at end of file
+    serialTag: str
+
+class ReportInfo(TypedDict):
+    serialTag: str
+    report: dict[str, Any]
\ No newline at end of file
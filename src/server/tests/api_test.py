@@ -2,7 +2,7 @@ from flask.testing import FlaskClient
 from werkzeug.test import TestResponse
 from configuration import ZIP_NAME
 from pathlib import Path
-from system.system_types import LogInfo, KeyInfo
+from system.system_types import LogInfo, KeyInfo, ReportInfo
 from system.zipper import Zip
 from zipfile import ZipFile
 from typing import Any
@@ -132,6 +132,105 @@ def test_add_log_fail(tmp_path: Path, client: FlaskClient):
 
     assert "missing key(s)" in msg.lower() and status == "error"
 
+def test_add_report(tmp_path: Path, client: FlaskClient):
+    api: str = "/api/report"
+
+    serial: str = "SERIAL1234"
+    report: dict[str, Any] = {
+        "serial_tag": serial,
+        "stages": [{"name": "packages", "status": "completed", "duration_seconds": 1.5}],
+        "packages": {"installed": ["package.pkg"], "skipped": [], "failed": []},
+    }
+    report_info: ReportInfo = {
+        "serialTag": serial,
+        "report": report,
+    }
+
+    res: TestResponse = client.post(api, json=report_info)
+    assert res.status_code == 200
+
+    files: list[str] = utils.get_dir_list(tmp_path / "reports" / serial)
+    assert len(files) == 1
+
+    with open(files[0], "r") as file:
+        assert json.load(file) == report
+
+def test_add_report_fail(client: FlaskClient):
+    api: str = "/api/report"
+
+    report_info: ReportInfo = {
+        "serialTag": "SERIAL1234",
+        "report": "not a report",
+    }
+
+    res: TestResponse = client.post(api, json=report_info)
+    assert res.status_code == 400
+
+    res = client.post(api, json={"serialTag": "SERIAL1234"})
+    content: dict[str, Any] = json.loads(res.data)
+
+    assert res.status_code > 300 and "missing key(s)" in content["content"].lower()
+
+def test_add_report_traversal(tmp_path: Path, client: FlaskClient):
+    api: str = "/api/report"
+
+    for serial in ["../../x", "..", ".", "", "a/b", "/tmp/x", 1234]:
+        report_info: ReportInfo = {
+            "serialTag": serial,
+            "report": {"serial_tag": "x"},
+        }
+
+        res: TestResponse = client.post(api, json=report_info)
+        assert res.status_code == 400
+
+    assert not (tmp_path / "x").exists()
+    assert not (tmp_path.parent / "x").exists()
+    assert len(list(tmp_path.glob("**/*.json"))) == 0
+
+def test_add_report_same_second(tmp_path: Path, client: FlaskClient):
+    api: str = "/api/report"
+    serial: str = "SERIAL1234"
+
+    for i in range(3):
+        res: TestResponse = client.post(api, json={"serialTag": serial, "report": {"run": i}})
+        assert res.status_code == 200
+
+    files: list[str] = utils.get_dir_list(tmp_path / "reports" / serial)
+    assert len(files) == 3
+
+def test_get_device_config(tmp_path: Path, client: FlaskClient):
+    serial: str = "SERIAL1234"
+    overlay: dict[str, Any] = {
+        "profile": "loaner",
+        "packages": {"extra.pkg": []},
+    }
+
+    configs_path: Path = tmp_path / "device-configs"
+    configs_path.mkdir(parents=True)
+    with open(configs_path / f"{serial}.json", "w") as file:
+        json.dump(overlay, file)
+
+    res: TestResponse = client.get(f"/api/config/{serial}")
+    assert res.status_code == 200
+
+    content: dict[str, Any] = json.loads(res.data)
+    assert content["status"] == "success" and content["content"] == overlay
+
+def test_get_device_config_missing(tmp_path: Path, client: FlaskClient):
+    res: TestResponse = client.get("/api/config/SERIAL1234")
+    assert res.status_code == 200
+
+    content: dict[str, Any] = json.loads(res.data)
+    assert content["status"] == "error" and content["content"] == {}
+
+    configs_path: Path = tmp_path / "device-configs"
+    configs_path.mkdir(parents=True)
+    with open(configs_path / "SERIAL5678.json", "w") as file:
+        file.write("[1, 2]")
+
+    res = client.get("/api/config/SERIAL5678")
+    assert res.status_code == 500
+
 def test_create_zip(tmp_path: Path, client: FlaskClient):
     response: TestResponse = client.get(f"/api/packages/{ZIP_NAME}")
 
@@ -11,6 +11,8 @@ import pytest
 def app_(tmp_path: Path):
     test_config: Config = {
         "keys_path": tmp_path / "keys",
+        "reports_path": tmp_path / "reports",
+        "device_configs_path": tmp_path / "device-configs",
         "log_path": tmp_path / "logs",
         "log_server_path": tmp_path / "logs" / "server",
         "log_levels": {"stream_level": 10},